@@ -253,6 +253,7 @@ JSON_MODE=1
 URL$=""
 JSON$="/tables"
 dummy$=""
+myToken$="" ' Session token from joining a table, sent with every state and move request
 
 ' Initialize strings and Arrays - this reserves their space in memory so NInput can write to them
 Dim TableCurrentPlayers(6),TableMaxPlayers(6),TableStatus(6),PlayerStatus(5),PlayerHandCount(5),PlayerWhiteTokens(5),PlayerBlackTokens(5)
@@ -327,6 +328,8 @@ PROC ClearState
   PlayerIndex=0
   dealt=0
   leaveTableFlag=0
+  AmHost=0 ' Set from the hn field, only the host can start the game
+  AmReady=0 ' Set once we have told the server we are ready to play
   countdown =60
   jiffy=0
 EndProc
@@ -357,6 +360,7 @@ Proc MainLoop
       ENDIF
       @ReadKeyPresses
     UNTIL GameStatus(tablenumber)=3
+        AmReady=0 ' Say so again for the next game
         @readGameState
         @DrawGameState
     REPEAT ' loop until the round ends
@@ -487,6 +491,7 @@ PROC TableSelection
   @NInput &dummy$ : @NInput &dummy$:TableCurrentPlayers(INDEX)=VAL(dummy$)
   @NInput &dummy$ : @NInput &dummy$:TableMaxPlayers(INDEX)=VAL(dummy$)
   @NInput &dummy$ : @NInput &dummy$:TableStatus(INDEX)=VAL(dummy$)
+  @NInput &dummy$ : @NInput &dummy$ ' Spectators watching the table (not shown)
   INC INDEX
   loop 
   @EnableDoubleBuffer 
@@ -552,6 +557,7 @@ Proc JoinTable
   @CallFujiNet ' Call the FujiNet API to join the table
   @NInputInit UNIT, &responseBuffer ' Initialize reading the api response
   @NInput &dummy$ ' Read the response from the FujiNet API
+  if dummy$="tk" then @NInput &myToken$ ' Keep the session token for reading the state and making moves
 EndProc
 
 PROC ReadKeyPresses
@@ -559,9 +565,13 @@ PROC ReadKeyPresses
   if K=224 or K=225 or K=229 or K=231 or K=226 or K=228 or K=204 or K=255 or K=197 or k=199
     @CheckVaildMove K
     @ClearKeyQueue
-  EliF K=193 AND GameStatus(tablenumber)=2
+  EliF K=193 AND (GameStatus(tablenumber)=1 OR GameStatus(tablenumber)=2)
     @GoodBeep
-    @StartGame
+    if AmHost=1
+      @StartGame
+    else
+      @ReadyToPlay ' Everyone else says they are ready, the game starts once all the humans are
+    endif
     @readGameState
     @ClearKeyQueue
   Elif K=237
@@ -875,6 +885,12 @@ ENDPROC
 
 Proc LeaveGame
  ' do whatever is needed to leave the current game table and return to table selection
+ JSON$="/leave?table="
+ JSON$=+TableID$(TableNumber)
+ JSON$=+"&tk="
+ JSON$=+myToken$
+ @CallFujiNet ' Tell the server we have gone so our seat is freed up (or handed to an AI player) straight away
+ myToken$=""
  leaveTableFlag=1
  GameStatus(tablenumber)=5
 endproc
@@ -886,6 +902,8 @@ PROC ReadGameState
   JSON$=+TableID$(TableNumber) 
   JSON$=+"&player="
   JSON$=+MyName$
+  JSON$=+"&tk="
+  JSON$=+myToken$
   ' JSON$="/state?table=ai1&player=SIMON"
   @CallFujiNet ' Call the FujiNet API to join the table
   @NInputInit UNIT, &responseBuffer ' Initialize reading the api response
@@ -937,6 +955,12 @@ PROC ReadGameState
     PlayerBlackTokens(INDEX)=VAL(value$)
   elif key$="ph" 
     PlayerHand$(INDEX)=value$   
+  elif key$="hn" ' The host comes after the Player Array
+    if value$=MyName$
+      AmHost=1
+    else
+      AmHost=0
+    endif
   elif key$="pvm" 
     PlayerValidMoves$(INDEX)=value$
     PlayerScore(INDEX)=(PlayerBlackTokens(INDEX)*10)+PlayerWhiteTokens(INDEX)
@@ -969,6 +993,8 @@ PROC PlayMove
   JSON$=+TableID$(TableNumber)
   JSON$=+"&player="
   JSON$=+MyName$
+  JSON$=+"&tk="
+  JSON$=+myToken$
   JSON$=+"&VM=" 
   JSON$=+move$
   @CallFujiNet
@@ -977,7 +1003,29 @@ ENDPROC
 PROC StartGame
   JSON$="/start?table="
   JSON$=+TableID$(TableNumber)
+  JSON$=+"&tk=" ' Only the host can start the game
+  JSON$=+myToken$
+  @CallFujiNet
+  @NInputInit UNIT, &responseBuffer
+  @NInput &dummy$
+  @CheckErrors
+  if ok<>1 then pause 120 ' Leave the message up long enough to read
+ENDPROC
+
+PROC ReadyToPlay
+  JSON$="/ready?table="
+  JSON$=+TableID$(TableNumber)
+  JSON$=+"&tk="
+  JSON$=+myToken$
   @CallFujiNet
+  @NInputInit UNIT, &responseBuffer
+  @NInput &dummy$
+  @CheckErrors
+  if ok=1
+    AmReady=1
+  else
+    pause 120 ' Leave the message up long enough to read
+  endif
 ENDPROC
 
 Proc DealCards
@@ -1006,10 +1054,14 @@ ENDPROC
 PROC CheckErrors
   ' Check data returned from FujiNet to see if it was successful or not
   ' and display appropriate message
+  ' Errors come back as {"err":code,"msg":message}, the codes are listed by /errors on the server
   ok = 1
-  if len(dummy$) > 0 then
-  @POS 0,0
-  _ERR=VAL(dummy$[5,1])
+  _ERR=0
+  if dummy$="err"
+    @NInput &dummy$
+    _ERR=VAL(dummy$)
+  endif
+  if _ERR>0 then @POS 0,0
   if _ERR=1 
     ok = 0
     @PrintUpper &" You need to specify a valid table"
@@ -1035,16 +1087,21 @@ PROC CheckErrors
     @PrintUpper &" is full, please try a different table"
   elif _ERR=6 
     ok = 0
-    @PrintUpper &"Must specify both table and player name"
- elif _ERR=7 
-    ok = 0
-    @PrintUpper &"Player not found at this table"
+    @PrintUpper &"Must specify your session token"
   elif _ERR=8 
     ok = 0
-    @PrintUpper &"Round is not over yet, no results available"
+    @PrintUpper &"Not an AI level"
   elif _ERR=9 
     ok = 0
-    @PrintUpper &"No human players at this table"
+    @PrintUpper &"Your session has expired,"
+    @PrintUpper &"please join the table again"
+  elif _ERR=20
+    ok = 0
+    @PrintUpper &"Only the host can start the game,"
+    @PrintUpper &"press S to say you are ready"
+  elif _ERR>9
+    ok = 0
+    @PrintUpper &"Sorry: the server said no"
   else
   ok = 1
   endif
@@ -1154,7 +1211,13 @@ PROC DrawGameState
   @DrawPlayers
   if LastMovePlayed$="Waiting for players to join"
   @POS 2,3: @Print &"PLEASE WAIT FOR OTHER PLAYERS TO JOIN"
-  @POS 3,4: @Print &"OR PRESS S TO START WITH AI PLAYERS" 
+  if AmHost=1
+    @POS 3,4: @Print &"OR PRESS S TO START WITH AI PLAYERS" 
+  elif AmReady=1
+    @POS 2,4: @Print &"READY: WAITING FOR THE HOST TO START"
+  else
+    @POS 2,4: @Print &"OR PRESS S WHEN YOU ARE READY TO PLAY"
+  endif
   @DrawCard 17,9,8 ' Draw Deck
   
   @DrawBufferEnd
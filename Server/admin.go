@@ -0,0 +1,27 @@
+package main
+
+import (
+	"crypto/subtle"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The key needed to use the admin endpoints, set from the ADMIN_KEY environment variable.
+// The admin endpoints are turned off when it isn't set.
+var adminKey string
+
+// requireAdmin only lets a request through if it has the admin key in the X-Admin-Key header
+// (not the query string, the request log would give the key away)
+func requireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if adminKey == "" {
+			replyError(c, ERR_ADMIN_OFF, "")
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("X-Admin-Key")), []byte(adminKey)) != 1 {
+			replyError(c, ERR_ADMIN_KEY, "")
+			return
+		}
+		c.Next()
+	}
+}
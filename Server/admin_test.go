@@ -0,0 +1,73 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestDevviewNeedsAdminKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setUpTables()
+	router := newRouter()
+
+	adminKey = ""
+	if status, _ := get(router, "/devview?table=ai1"); status != http.StatusForbidden {
+		t.Errorf("devview with no ADMIN_KEY set = %d, want %d", status, http.StatusForbidden)
+	}
+
+	adminKey = "sekrit"
+	defer func() { adminKey = "" }()
+	tests := []struct {
+		name   string
+		url    string
+		header string
+		status int
+	}{
+		{"no key", "/devview?table=ai1", "", http.StatusUnauthorized},
+		{"wrong key", "/devview?table=ai1", "guess", http.StatusUnauthorized},
+		{"key in the query", "/devview?table=ai1&key=sekrit", "", http.StatusUnauthorized},
+		{"key in the header", "/devview?table=ai1", "sekrit", http.StatusOK},
+		{"all tables", "/devview", "sekrit", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if status, _ := getAsAdmin(router, tt.url, tt.header); status != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.name, status, tt.status)
+		}
+	}
+}
+
+// getAsAdmin is get with the admin key in the X-Admin-Key header (left out if key is empty)
+func getAsAdmin(router *gin.Engine, url string, key string) (int, []byte) {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, url, nil)
+	if key != "" {
+		request.Header.Set("X-Admin-Key", key)
+	}
+	router.ServeHTTP(recorder, request)
+	return recorder.Code, recorder.Body.Bytes()
+}
+
+func TestPublicViewHidesCards(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setUpTables()
+	router := newRouter()
+	bob := join(t, router, "ai1", "BOB")
+	get(router, "/start?table=ai1&tk="+bob)
+
+	status, body := get(router, "/view?table=ai1")
+	if status != http.StatusOK || !strings.Contains(string(body), `"n":"BOB"`) || !strings.Contains(string(body), `"nc":6`) {
+		t.Fatalf("view = %d %s, want BOB with 6 cards", status, body)
+	}
+	for _, secret := range []string{"Hand", "Maindeck", "Token", "cv", "pvm", tables[1].game.Players[0].ShortToken} {
+		if strings.Contains(string(body), secret) {
+			t.Errorf("public view gives away %q: %s", secret, body)
+		}
+	}
+	if status, body := get(router, "/view"); status != http.StatusOK || strings.Count(string(body), `"table"`) != len(tables) {
+		t.Errorf("view of all tables = %d %s", status, body)
+	}
+}
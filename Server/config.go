@@ -0,0 +1,160 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"time"
+
+	"BunnyHop/server/engine"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The tables the server runs when there isn't a tables file
+var defaultTables = []engine.GameTable{
+	{Table: "garden", Name: "The Garden", CurPlayers: 0, MaxPlayers: 6, MaxBots: 5, BotLevel: engine.AI_MEDIUM, Status: 0},
+	{Table: "ai1", Name: "AI Room - 1 bots", CurPlayers: 0, MaxPlayers: 6, MaxBots: 1, BotLevel: engine.AI_MEDIUM, Status: 0},
+	{Table: "ai2", Name: "AI Room - 2 bots", CurPlayers: 0, MaxPlayers: 6, MaxBots: 2, BotLevel: engine.AI_MEDIUM, Status: 0},
+	{Table: "ai3", Name: "AI Room - 3 bots", CurPlayers: 0, MaxPlayers: 6, MaxBots: 3, BotLevel: engine.AI_MEDIUM, Status: 0},
+	{Table: "ai4", Name: "AI Room - 4 bots", CurPlayers: 0, MaxPlayers: 6, MaxBots: 4, BotLevel: engine.AI_MEDIUM, Status: 0},
+	{Table: "ai5", Name: "AI Room - 5 bots", CurPlayers: 0, MaxPlayers: 6, MaxBots: 5, BotLevel: engine.AI_MEDIUM, Status: 0},
+	{Table: "cave", Name: "Cave of Caerbannog", CurPlayers: 0, MaxPlayers: 6, MaxBots: 5, BotLevel: engine.AI_EXPERT, BotThinkTime: time.Second, HumansOnly: true, Status: 0},
+}
+
+// The most players the clients have room to show at a table
+const maxTablePlayers = 6
+
+// The shortest move time a table can set: an expert AI player's whole turn, after waiting up to a tick of the game loop for it to begin
+const minMoveTime = engine.MaxAITurnTime + tableTickInterval
+
+// Table ids end up in URLs and file names (see store.go), so they are kept simple
+var tableIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// tableConfig is a table as it is written in the tables file (YAML, or JSON as YAML reads that too)
+type tableConfig struct {
+	ID            string `yaml:"id"`            // Used in the URLs, EG: /join?table=garden
+	Name          string `yaml:"name"`          // Shown in the table list and the lobby
+	MaxPlayers    int    `yaml:"maxPlayers"`    // Seats at the table, humans and AI players (up to 6)
+	MaxBots       int    `yaml:"maxBots"`       // AI players added to fill empty seats when the game starts
+	BotLevel      string `yaml:"botLevel"`      // easy, medium, hard or expert (medium if not set)
+	ThinkTime     string `yaml:"thinkTime"`     // How long the expert AI players can think about each move, EG: 1s (optional)
+	Variant       string `yaml:"variant"`       // The rules the table plays by (standard if not set)
+	HumansOnly    bool   `yaml:"humansOnly"`    // No AI players are added once 2 or more humans have joined
+	WaitTime      string `yaml:"waitTime"`      // How long to wait for everyone to be ready before starting with AI players, EG: 2m (45s if not set)
+	MoveTime      string `yaml:"moveTime"`      // How long each move can take, EG: 15s (60s if not set)
+	TimeBank      string `yaml:"timeBank"`      // Extra time each player can use over the game when they go over the move time, EG: 2m (none if not set)
+	Extensions    int    `yaml:"extensions"`    // Grace extensions each player gets once their time bank has run out, before they are folded
+	ExtensionTime string `yaml:"extensionTime"` // How long each grace extension is, EG: 30s (15s if not set)
+}
+
+// tablesFile is the layout of the whole tables file
+type tablesFile struct {
+	Tables []tableConfig `yaml:"tables"`
+}
+
+// loadTablesConfig reads the tables from the file, or returns the default tables if the file doesn't exist
+func loadTablesConfig(path string) ([]engine.GameTable, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Println("No tables file at", path, "using the default tables")
+		return defaultTables, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseTablesConfig(data)
+}
+
+// parseTablesConfig turns the tables file into the tables' set up, checking every table makes sense
+func parseTablesConfig(data []byte) ([]engine.GameTable, error) {
+	var file tablesFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if len(file.Tables) == 0 {
+		return nil, errors.New("no tables in the tables file")
+	}
+	tableList := make([]engine.GameTable, 0, len(file.Tables))
+	seen := map[string]bool{}
+	for _, config := range file.Tables {
+		table, err := config.gameTable()
+		if err != nil {
+			return nil, fmt.Errorf("table %q: %w", config.ID, err)
+		}
+		if seen[table.Table] {
+			return nil, fmt.Errorf("table %q is in the tables file twice", table.Table)
+		}
+		seen[table.Table] = true
+		tableList = append(tableList, table)
+	}
+	return tableList, nil
+}
+
+// gameTable checks the table's settings and turns them into the engine's table set up
+func (config tableConfig) gameTable() (engine.GameTable, error) {
+	table := engine.GameTable{
+		Table:      config.ID,
+		Name:       config.Name,
+		MaxPlayers: config.MaxPlayers,
+		MaxBots:    config.MaxBots,
+		BotLevel:   config.BotLevel,
+		Variant:    config.Variant,
+		HumansOnly: config.HumansOnly,
+	}
+	if table.BotLevel == "" {
+		table.BotLevel = engine.AI_MEDIUM
+	}
+	if table.Name == "" {
+		table.Name = table.Table
+	}
+	if config.ThinkTime != "" {
+		think, err := time.ParseDuration(config.ThinkTime)
+		if err != nil {
+			return table, fmt.Errorf("thinkTime: %w", err)
+		}
+		table.BotThinkTime = think
+	}
+	for _, setting := range []struct {
+		name  string
+		value string
+		time  *time.Duration
+	}{
+		{"waitTime", config.WaitTime, &table.WaitTime},
+		{"moveTime", config.MoveTime, &table.MoveTime},
+		{"timeBank", config.TimeBank, &table.TimeBank},
+		{"extensionTime", config.ExtensionTime, &table.ExtensionTime},
+	} {
+		if setting.value == "" {
+			continue
+		}
+		length, err := time.ParseDuration(setting.value)
+		if err != nil {
+			return table, fmt.Errorf("%s: %w", setting.name, err)
+		}
+		if length <= 0 {
+			return table, fmt.Errorf("%s must be more than 0", setting.name)
+		}
+		*setting.time = length
+	}
+	table.Extensions = config.Extensions
+	switch {
+	case !tableIDPattern.MatchString(table.Table):
+		return table, errors.New("the id must be lower case letters, numbers and dashes")
+	case table.MaxPlayers < 1 || table.MaxPlayers > maxTablePlayers:
+		return table, fmt.Errorf("maxPlayers must be between 1 and %d", maxTablePlayers)
+	case table.MaxBots < 0 || table.MaxBots >= table.MaxPlayers:
+		return table, errors.New("maxBots must leave at least one seat for a human")
+	case engine.AIStrategies[table.BotLevel] == nil:
+		return table, errors.New("botLevel must be easy, medium, hard or expert")
+	case table.Variant != "" && !slices.Contains(engine.Variants, table.Variant):
+		return table, fmt.Errorf("unknown variant, the variants are %v", engine.Variants)
+	case table.Extensions < 0:
+		return table, errors.New("extensions can't be less than 0")
+	case table.MoveTime != 0 && table.MoveTime < minMoveTime:
+		return table, fmt.Errorf("moveTime must be at least %v so the AI players have time to move", minMoveTime)
+	}
+	return table, nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"BunnyHop/server/engine"
+)
+
+func TestShippedTablesFileMatchesTheDefaults(t *testing.T) {
+	data, err := os.ReadFile("tables.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	configs, err := parseTablesConfig(data)
+	if err != nil {
+		t.Fatalf("tables.yaml: %v", err)
+	}
+	if !reflect.DeepEqual(configs, defaultTables) {
+		t.Errorf("tables.yaml =\n%+v\nwant the default tables\n%+v", configs, defaultTables)
+	}
+}
+
+func TestParseTablesConfig(t *testing.T) {
+	// JSON works too, and anything left out gets a sensible default
+	configs, err := parseTablesConfig([]byte(`{"tables": [{"id": "river", "maxPlayers": 4, "maxBots": 3, "thinkTime": "500ms", "waitTime": "2m", "moveTime": "10s", "timeBank": "1m", "extensions": 2, "humansOnly": true}]}`))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	river := configs[0]
+	if river.Table != "river" || river.Name != "river" || river.BotLevel != "medium" || river.BotThinkTime != 500*time.Millisecond || river.WaitTime != 2*time.Minute || !river.HumansOnly {
+		t.Errorf("river = %+v", river)
+	}
+	if river.MoveTime != 10*time.Second || river.TimeBank != time.Minute || river.Extensions != 2 || river.ExtensionTime != 0 {
+		t.Errorf("river = %+v", river)
+	}
+
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr string
+	}{
+		{"no tables", "tables: []", "no tables"},
+		{"bad id", "tables: [{id: The Garden, maxPlayers: 6}]", "id must be"},
+		{"too many players", "tables: [{id: big, maxPlayers: 8}]", "maxPlayers"},
+		{"no room for humans", "tables: [{id: bots, maxPlayers: 4, maxBots: 4}]", "maxBots"},
+		{"unknown level", "tables: [{id: a, maxPlayers: 4, botLevel: godlike}]", "botLevel"},
+		{"unknown variant", "tables: [{id: a, maxPlayers: 4, variant: speed}]", "variant"},
+		{"bad think time", "tables: [{id: a, maxPlayers: 4, thinkTime: soon}]", "thinkTime"},
+		{"no wait time", "tables: [{id: a, maxPlayers: 4, waitTime: 0s}]", "waitTime"},
+		{"bad move time", "tables: [{id: a, maxPlayers: 4, moveTime: -5s}]", "moveTime"},
+		{"move time too short for the AI", "tables: [{id: a, maxPlayers: 4, moveTime: 3s}]", "moveTime"},
+		{"negative extensions", "tables: [{id: a, maxPlayers: 4, extensions: -1}]", "extensions"},
+		{"same table twice", "tables: [{id: a, maxPlayers: 4}, {id: a, maxPlayers: 2}]", "twice"},
+	}
+	for _, tt := range tests {
+		if _, err := parseTablesConfig([]byte(tt.yaml)); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: got error %v, want one about %q", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMissingTablesFileUsesTheDefaults(t *testing.T) {
+	configs, err := loadTablesConfig(t.TempDir() + "/nothing.yaml")
+	if err != nil || len(configs) != len(defaultTables) {
+		t.Errorf("loadTablesConfig = %d tables, %v", len(configs), err)
+	}
+}
+
+func TestExpertAIMovesAtTheShortestMoveTime(t *testing.T) {
+	configs, err := parseTablesConfig([]byte(fmt.Sprintf("tables: [{id: quick, maxPlayers: 3, maxBots: 2, botLevel: expert, thinkTime: 5s, moveTime: %s}]", minMoveTime)))
+	if err != nil {
+		t.Fatalf("the shortest move time was turned down: %v", err)
+	}
+	game := engine.NewSeededGame(configs[0], 1)
+	game.Join("Alice", "")
+	game.Start()
+	game.ApplyMove(0, "F") // Over to the first expert AI player
+	start := game.StartTime
+
+	// The game loop takes the turn out on its first tick after the AI move delay,
+	// and the AI thinks for as long as it is allowed before the move is made
+	if events := game.Tick(start.Add(engine.AIMoveDelay + tableTickInterval - time.Millisecond)); len(events) != 0 {
+		t.Fatalf("Tick made the expert AI's move, events %v", events)
+	}
+	turn, ok := game.NextAITurn(start.Add(engine.AIMoveDelay + tableTickInterval - time.Millisecond))
+	if !ok {
+		t.Fatal("the expert AI's turn wasn't taken out")
+	}
+	if events := game.Tick(start.Add(minMoveTime - time.Millisecond)); len(events) != 0 {
+		t.Fatalf("the expert AI ran out of time while it was thinking, events %v", events)
+	}
+	events := game.FinishAITurn(turn, turn.View.ValidMoves[:1])
+	if len(events) != 1 || events[0].Type == engine.EVENT_FOLDED || events[0].Player != game.Players[turn.Player].Name {
+		t.Errorf("the expert AI's move wasn't made, events %v", events)
+	}
+}
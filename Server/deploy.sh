@@ -1,2 +1,15 @@
+# Games are saved to STATE_DIR (default ./state) after every move so they survive a restart. Cloud Run's own disk
+# is lost with the instance, so the saves go in the STATE_BUCKET Cloud Storage bucket mounted at /state
+# (Cloud Storage volumes need the second generation execution environment). Private tables are never saved.
+# /devview is turned off unless ADMIN_KEY is set (e.g. --set-env-vars=ADMIN_KEY=...), send it as the X-Admin-Key header
+# Each table's game loop runs in the background between requests (timers, AI moves and folding slow players),
+# so the CPU is kept on all the time the instance is up (--no-cpu-throttling) rather than only while a request is open
+# The tables come from TABLES_FILE (default tables.yaml), send the server a SIGHUP to reload it without a restart
+STATE_BUCKET=bunnyhopnz-state
 gcloud config set project bunnyhopnz
-gcloud run deploy bunnyhopnz --source . --region=asia-southeast1 --min-instances=0 --max-instances=1
\ No newline at end of file
+gcloud storage buckets describe gs://$STATE_BUCKET > /dev/null 2>&1 || gcloud storage buckets create gs://$STATE_BUCKET --location=asia-southeast1
+gcloud run deploy bunnyhopnz --source . --region=asia-southeast1 --min-instances=0 --max-instances=1 \
+  --execution-environment=gen2 --no-cpu-throttling \
+  --add-volume=name=state,type=cloud-storage,bucket=$STATE_BUCKET \
+  --add-volume-mount=volume=state,mount-path=/state \
+  --update-env-vars=STATE_DIR=/state
@@ -0,0 +1,344 @@
+package engine
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AI difficulty levels that can be set on a table
+const (
+	AI_EASY   = "easy"
+	AI_MEDIUM = "medium"
+	AI_HARD   = "hard"
+	AI_EXPERT = "expert" // Monte Carlo simulation, see montecarlo.go
+)
+
+// AIView is everything an AI player is allowed to see when it is choosing a move
+type AIView struct {
+	Hand       Deck           // The AI player's own hand
+	Discard    Card           // The top card of the discard pile
+	ValidMoves string         // The moves the AI player can make (e.g. "45DF")
+	DrawDeck   int            // Number of cards left in the draw deck
+	Score      int            // The AI player's score so far this game
+	Seen       [8]int         // How many of each card value (1-7) have been played onto the discard pile this round
+	Opponents  []OpponentView // What can be seen of the other players at the table, in turn order after the AI player
+}
+
+// OpponentView is what an AI player can see of another player at the table
+type OpponentView struct {
+	NumCards int
+	Status   Status
+	Score    int
+}
+
+// AIStrategy chooses a move for an AI player, the move returned must be one of view.ValidMoves
+type AIStrategy interface {
+	ChooseMove(view AIView) string
+}
+
+// The strategies available for each difficulty level
+var AIStrategies = map[string]AIStrategy{
+	AI_EASY:   easyAI{},
+	AI_MEDIUM: mediumAI{},
+	AI_HARD:   hardAI{},
+	AI_EXPERT: MonteCarloAI{ThinkTime: defaultAIThinkTime},
+}
+
+// aiMove asks the AI strategy for the AI player's next move
+func (g *Game) aiMove(playerIndex int) string {
+	g.Players[playerIndex].ValidMove = g.ValidMoves(playerIndex) // Ensure the AI player has valid moves set
+	// check if AI player has valid moves (just encase)
+	if len(g.Players[playerIndex].ValidMove) == 0 {
+		return "F" // If no valid moves, fold the AI player
+	}
+
+	move := g.aiStrategy(playerIndex).ChooseMove(g.makeAIView(playerIndex))
+	return g.checkAIMove(playerIndex, move)
+}
+
+// aiStrategy is the strategy for the AI player, using the player's own level or the table's level if it doesn't have one
+func (g *Game) aiStrategy(playerIndex int) AIStrategy {
+	level := g.Players[playerIndex].BotLevel
+	if level == "" {
+		level = g.Table.BotLevel
+	}
+	strategy, ok := AIStrategies[level]
+	if !ok {
+		strategy = AIStrategies[AI_MEDIUM] // Fall back to medium if the table has no (or an unknown) level set
+	}
+	if easy, ok := strategy.(easyAI); ok {
+		easy.rng = g.rng // Use the table's own random numbers, so a seeded game plays out the same every time
+		strategy = easy
+	}
+	if expert, ok := strategy.(MonteCarloAI); ok && g.Table.BotThinkTime > 0 {
+		expert.ThinkTime = g.Table.BotThinkTime // Use the table's thinking budget
+		strategy = expert
+	}
+	return strategy
+}
+
+// checkAIMove never trusts the strategy to return a legal move, it falls back to the first valid move if it didn't
+func (g *Game) checkAIMove(playerIndex int, move string) string {
+	if len(move) != 1 || !strings.Contains(g.Players[playerIndex].ValidMove, move) {
+		move = string(g.Players[playerIndex].ValidMove[0])
+	}
+	return move
+}
+
+// AITurn is an AI player's turn taken out of the game so the AI can think about it without holding up the table,
+// see NextAITurn and FinishAITurn
+type AITurn struct {
+	Player   int        // Index of the AI player whose turn it is
+	Version  int        // The game's Version when the turn was taken out, the move is only made if nothing has happened since
+	Strategy AIStrategy // The strategy that chooses the move
+	View     AIView     // A copy of what the AI player can see, so it can be used without the table lock
+}
+
+// Choose asks the strategy for the move, it can take as long as the strategy likes to think
+// and doesn't touch the game, so it can be called without holding the table lock
+func (turn AITurn) Choose() string {
+	return turn.Strategy.ChooseMove(turn.View)
+}
+
+// NextAITurn takes out the turn of an AI player that thinks about its move (the expert AI), once the AI move delay is up,
+// so it can be chosen without holding the table lock. Returns false if it isn't the turn of an AI player that thinks
+func (g *Game) NextAITurn(now time.Time) (AITurn, bool) {
+	if g.Table.Status != TABLE_PLAYING || now.Sub(g.StartTime) < AIMoveDelay {
+		return AITurn{}, false
+	}
+	for i := range g.Players {
+		if g.Players[i].Status != STATUS_PLAYING || g.Players[i].Human {
+			continue
+		}
+		strategy := g.aiStrategy(i)
+		if _, thinks := strategy.(MonteCarloAI); !thinks {
+			return AITurn{}, false // Tick makes the quick AI players' moves itself
+		}
+		g.Players[i].ValidMove = g.ValidMoves(i)
+		return AITurn{Player: i, Version: g.Version, Strategy: strategy, View: g.makeAIView(i)}, true
+	}
+	return AITurn{}, false
+}
+
+// FinishAITurn makes the move the AI chose for the turn taken out by NextAITurn, unless something has happened
+// at the table since (the Version has changed or it is no longer that AI player's turn), then the move is dropped
+func (g *Game) FinishAITurn(turn AITurn, move string) []Event {
+	if g.Version != turn.Version || g.Table.Status != TABLE_PLAYING || turn.Player >= len(g.Players) {
+		return nil
+	}
+	player := g.Players[turn.Player]
+	if player.Status != STATUS_PLAYING || player.Human || len(player.ValidMove) == 0 {
+		return nil
+	}
+	return g.doMove(turn.Player, g.checkAIMove(turn.Player, move))
+}
+
+// makeAIView builds the view of the game that the AI player at playerIndex is allowed to see
+func (g *Game) makeAIView(playerIndex int) AIView {
+	player := g.Players[playerIndex]
+	view := AIView{
+		Hand:       append(Deck{}, player.Hand...),
+		Discard:    g.Discard,
+		ValidMoves: player.ValidMove,
+		DrawDeck:   g.NumCards,
+		Score:      player.Score,
+		Seen:       g.SeenCards,
+	}
+	for i := 1; i < len(g.Players); i++ {
+		other := g.Players[(playerIndex+i)%len(g.Players)]
+		view.Opponents = append(view.Opponents, OpponentView{NumCards: other.NumCards, Status: other.Status, Score: other.Score})
+	}
+	return view
+}
+
+// easyAI plays any valid card or draws at random, and only folds when it has nothing else it can do
+type easyAI struct {
+	rng *rand.Rand // Where the random choices come from, nil for the shared random numbers (set for a repeatable game)
+}
+
+func (ai easyAI) ChooseMove(view AIView) string {
+	options := strings.ReplaceAll(view.ValidMoves, "F", "")
+	if options == "" {
+		return "F"
+	}
+	if ai.rng != nil {
+		return string(options[ai.rng.Intn(len(options))])
+	}
+	return string(options[rand.Intn(len(options))])
+}
+
+// mediumAI always plays the card that lowers its hand score the most,
+// and when it can't play it weighs what its hand is worth against how many cards are left to draw
+type mediumAI struct{}
+
+func (mediumAI) ChooseMove(view AIView) string {
+	if play := bestPlay(view); play != "" {
+		return play
+	}
+	if !strings.Contains(view.ValidMoves, "D") {
+		return "F"
+	}
+	// Fold on a cheap hand, or when the deck is getting too small to hope for a card we can play
+	handScore := ScoreHand(view.Hand)
+	if handScore <= 5 || (view.DrawDeck < 8 && handScore <= 12) {
+		return "F"
+	}
+	return "D"
+}
+
+// hardAI keeps track of which cards have been seen this round, so it knows how likely a draw is to help,
+// looks for a run of plays that gets rid of its whole hand, prefers plays that keep its next turn open,
+// and folds to lock in a low score when an opponent is about to go out
+type hardAI struct{}
+
+func (hardAI) ChooseMove(view AIView) string {
+	handScore := ScoreHand(view.Hand)
+
+	// Pick the play that leaves the lowest hand score, breaking ties on how many cards we could follow up with.
+	// A play that the rest of the hand can follow all the way out beats them all, going out hands back a chip
+	bestMove := ""
+	bestScore, bestFollowUps := 0, -1
+	for _, move := range view.ValidMoves {
+		value, err := strconv.Atoi(string(move))
+		if err != nil {
+			continue // Not a card play
+		}
+		remaining := removeValue(view.Hand, value)
+		score := ScoreHand(remaining)
+		if canPlayOut(remaining, value) {
+			score = -1
+		}
+		followUps := countPlayable(remaining, value)
+		if bestFollowUps == -1 || score < bestScore || (score == bestScore && followUps > bestFollowUps) {
+			bestMove, bestScore, bestFollowUps = string(move), score, followUps
+		}
+	}
+	if bestMove != "" && len(view.Hand) == 1 {
+		return bestMove // Going out beats anything else
+	}
+
+	// If an opponent is about to go out and our hand is already cheap, get out now rather than risk it
+	for _, opponent := range view.Opponents {
+		if opponent.Status != STATUS_FOLDED && opponent.NumCards == 1 && handScore <= 3 && strings.Contains(view.ValidMoves, "F") {
+			return "F"
+		}
+	}
+
+	if bestMove != "" {
+		return bestMove
+	}
+
+	if !strings.Contains(view.ValidMoves, "D") {
+		return "F"
+	}
+
+	// Work out the chance the next card drawn is one we can play, from the cards we haven't seen yet
+	unseen := unseenCards(view)
+	total := 0
+	for value := 1; value <= 7; value++ {
+		total += unseen[value]
+	}
+	if total == 0 {
+		return "F"
+	}
+	playable := unseen[view.Discard.Cardvalue] + unseen[NextCardValue(view.Discard.Cardvalue)]
+	chance := float64(playable) / float64(total)
+
+	// Lock in a cheap hand, otherwise keep drawing unless the deck is running out and the odds are against us
+	switch {
+	case handScore <= 3:
+		return "F"
+	case view.DrawDeck < 8 && handScore <= 12 && chance < 0.4:
+		return "F"
+	}
+	return "D"
+}
+
+// bestPlay returns the card play that leaves the lowest hand score, or "" if no card can be played
+func bestPlay(view AIView) string {
+	bestMove := ""
+	bestScore := 0
+	for _, move := range view.ValidMoves {
+		value, err := strconv.Atoi(string(move))
+		if err != nil {
+			continue // Not a card play
+		}
+		score := ScoreHand(removeValue(view.Hand, value))
+		if bestMove == "" || score < bestScore {
+			bestMove, bestScore = string(move), score
+		}
+	}
+	return bestMove
+}
+
+// removeValue returns a copy of the hand with one card of the given value removed
+func removeValue(hand Deck, value int) Deck {
+	remaining := Deck{}
+	removed := false
+	for _, card := range hand {
+		if card.Cardvalue == value && !removed {
+			removed = true
+			continue
+		}
+		remaining = append(remaining, card)
+	}
+	return remaining
+}
+
+// countPlayable counts the cards in the hand that could be played onto a discard of the given value
+func countPlayable(hand Deck, discardValue int) int {
+	count := 0
+	for _, card := range hand {
+		if card.Cardvalue == discardValue || card.Cardvalue == NextCardValue(discardValue) {
+			count++
+		}
+	}
+	return count
+}
+
+// canPlayOut checks if the hand could be played out card by card onto a discard of the given value,
+// each card the same value as the one before it or the next value up (if nobody else changes the discard in between)
+func canPlayOut(hand Deck, discardValue int) bool {
+	counts := [8]int{}
+	for _, card := range hand {
+		counts[card.Cardvalue]++
+	}
+	return playOut(counts, len(hand), discardValue)
+}
+
+// playOut is canPlayOut working on how many of each card value (1-7) are left in the hand
+func playOut(counts [8]int, left int, discardValue int) bool {
+	if left == 0 {
+		return true
+	}
+	for _, value := range []int{discardValue, NextCardValue(discardValue)} {
+		if counts[value] == 0 {
+			continue
+		}
+		counts[value]--
+		if playOut(counts, left-1, value) {
+			return true
+		}
+		counts[value]++
+	}
+	return false
+}
+
+// unseenCards counts how many of each card value (1-7) the AI player hasn't seen yet this round
+func unseenCards(view AIView) [8]int {
+	unseen := [8]int{}
+	for value := 1; value <= 7; value++ {
+		unseen[value] = 8 - view.Seen[value] // There are 8 of each card in the deck
+	}
+	for _, card := range view.Hand {
+		unseen[card.Cardvalue]--
+	}
+	for value := 1; value <= 7; value++ {
+		if unseen[value] < 0 {
+			unseen[value] = 0
+		}
+	}
+	return unseen
+}
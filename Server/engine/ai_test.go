@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// aiView sets up a game part way through a round with P1 to play and returns what P1 can see
+func aiView(discard int, hands ...string) AIView {
+	g := playingGame(discard, hands...)
+	g.Players[0].ValidMove = g.ValidMoves(0)
+	return g.makeAIView(0)
+}
+
+// playRound plays a dealt round with the strategy making every move, failing the test on any move that isn't valid
+func playRound(t *testing.T, strategy AIStrategy, seed int64) {
+	g := NewSeededGame(GameTable{Table: "test", MaxPlayers: 4}, seed)
+	for i := 0; i < 4; i++ {
+		g.Join(fmt.Sprintf("P%d", i+1), "")
+	}
+	g.Start()
+	for moves := 0; g.Table.Status == TABLE_PLAYING && !g.checkRoundEndCondtions(); moves++ {
+		if moves > 200 {
+			t.Fatalf("seed %d: the round didn't end after %d moves", seed, moves)
+		}
+		i := turn(g)
+		validMoves := g.ValidMoves(i)
+		g.Players[i].ValidMove = validMoves
+		move := strategy.ChooseMove(g.makeAIView(i))
+		if len(move) != 1 || !strings.Contains(validMoves, move) {
+			t.Fatalf("seed %d: chose %q, the valid moves were %q", seed, move, validMoves)
+		}
+		g.doMove(i, move)
+	}
+}
+
+func TestAIStrategiesOnlyMakeValidMoves(t *testing.T) {
+	strategies := map[string]AIStrategy{
+		AI_EASY:   easyAI{rng: rand.New(rand.NewSource(1))},
+		AI_MEDIUM: mediumAI{},
+		AI_HARD:   hardAI{},
+	}
+	for level, strategy := range strategies {
+		t.Run(level, func(t *testing.T) {
+			for seed := int64(1); seed <= 20; seed++ {
+				playRound(t, strategy, seed)
+			}
+		})
+	}
+}
+
+func TestEasyAI(t *testing.T) {
+	view := aiView(3, "34", "55")
+	a := easyAI{rng: rand.New(rand.NewSource(7))}
+	b := easyAI{rng: rand.New(rand.NewSource(7))}
+	for i := 0; i < 20; i++ {
+		move := a.ChooseMove(view)
+		if move == "F" {
+			t.Fatalf("folded with %q to choose from", view.ValidMoves)
+		}
+		if again := b.ChooseMove(view); again != move {
+			t.Fatalf("the same seed chose %q and then %q", move, again)
+		}
+	}
+	if move := a.ChooseMove(AIView{ValidMoves: "F"}); move != "F" {
+		t.Errorf("with only a fold to make it chose %q", move)
+	}
+}
+
+func TestMediumAI(t *testing.T) {
+	tests := []struct {
+		name     string
+		view     AIView
+		drawDeck int
+		want     string
+	}{
+		{"plays the card that leaves the lowest score", aiView(3, "34", "55"), 20, "4"},
+		{"folds a cheap hand rather than draw", aiView(5, "2", "55"), 20, "F"},
+		{"draws to an expensive hand", aiView(5, "77", "55"), 20, "D"},
+		{"folds when the deck is running out", aiView(5, "77", "55"), 5, "F"},
+	}
+	for _, tt := range tests {
+		tt.view.DrawDeck = tt.drawDeck
+		if move := (mediumAI{}).ChooseMove(tt.view); move != tt.want {
+			t.Errorf("%s: chose %q from %q, want %q", tt.name, move, tt.view.ValidMoves, tt.want)
+		}
+	}
+}
+
+func TestHardAI(t *testing.T) {
+	tests := []struct {
+		name     string
+		view     AIView
+		drawDeck int
+		want     string
+	}{
+		{"goes out with its last card", aiView(3, "4", "1"), 20, "4"},
+		{"plays the card that leaves the lowest score", aiView(1, "126", "5555"), 20, "2"},
+		{"plays the card that lets it go out next", aiView(3, "34", "5555"), 20, "3"},
+		{"folds a cheap hand when an opponent is about to go out", aiView(1, "12", "5"), 20, "F"},
+		{"folds a cheap hand rather than draw", aiView(5, "12", "55"), 20, "F"},
+		{"draws while there are plenty of cards left", aiView(2, "46", "55"), 20, "D"},
+		{"folds when the deck is running out and the odds are poor", aiView(2, "46", "55"), 4, "F"},
+		{"draws to an expensive hand even when the deck is running out", aiView(2, "67", "55"), 4, "D"},
+	}
+	for _, tt := range tests {
+		tt.view.DrawDeck = tt.drawDeck
+		if move := (hardAI{}).ChooseMove(tt.view); move != tt.want {
+			t.Errorf("%s: chose %q from %q, want %q", tt.name, move, tt.view.ValidMoves, tt.want)
+		}
+	}
+}
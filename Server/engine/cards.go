@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Deck represents a collection of cards.
+type Deck []Card
+
+// card represents a playing card with it's name and value
+type Card struct {
+	Cardvalue int    `json:"cv"`
+	Cardname  string `json:"cn"`
+}
+
+// The names of the cards, CardNames[0] is the One and CardNames[6] is the Llama
+var CardNames = []string{"One", "Two", "Three", "Four", "Five", "Six", "Llama"}
+
+// NewDeck creates a new Llama 56-card deck.
+func NewDeck() []Card {
+	deck := make([]Card, 56)
+
+	currentCard := 0
+	for value := 1; value <= 7; value++ {
+		for i := 0; i < 8; i++ {
+			deck[currentCard] = Card{Cardvalue: value, Cardname: CardNames[value-1]}
+			currentCard++
+		}
+	}
+	return deck
+}
+
+// NextCardValue returns the value that can be played on top of the given value (a Llama is followed by a One)
+func NextCardValue(value int) int {
+	if value >= 7 {
+		return 1
+	}
+	return value + 1
+}
+
+// ScoreHand returns what a hand would score if the round ended now (each value counts once, a Llama is 10)
+func ScoreHand(hand Deck) int {
+	counted := make(map[int]bool)
+	score := 0
+	for _, card := range hand {
+		if counted[card.Cardvalue] {
+			continue
+		}
+		counted[card.Cardvalue] = true
+		if card.Cardvalue == 7 {
+			score += 10
+		} else {
+			score += card.Cardvalue
+		}
+	}
+	return score
+}
+
+// HandSummary returns the hand as a string of card values (e.g. "1347") for sending to 8 bit computers
+func (hand Deck) HandSummary() string {
+	summary := ""
+	for _, card := range hand {
+		summary += strconv.Itoa(card.Cardvalue)
+	}
+	return strings.TrimSpace(summary)
+}
+
+// sortHand sorts the hand into card value order
+func (hand Deck) sortHand() {
+	sort.SliceStable(hand, func(i, j int) bool {
+		return hand[i].Cardvalue < hand[j].Cardvalue
+	})
+}
+
+// removeDuplicateCards returns the hand with only one card of each value
+func (hand Deck) removeDuplicateCards() Deck {
+	seen := make(map[int]bool)
+	uniqueHand := Deck{}
+
+	for _, card := range hand {
+		if !seen[card.Cardvalue] {
+			seen[card.Cardvalue] = true
+			uniqueHand = append(uniqueHand, card)
+		}
+	}
+	return uniqueHand
+}
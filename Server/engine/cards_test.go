@@ -0,0 +1,76 @@
+package engine
+
+import "testing"
+
+func TestNewDeck(t *testing.T) {
+	deck := NewDeck()
+	if len(deck) != 56 {
+		t.Fatalf("deck has %d cards, want 56", len(deck))
+	}
+	counts := map[int]int{}
+	for _, card := range deck {
+		counts[card.Cardvalue]++
+		if card.Cardname != CardNames[card.Cardvalue-1] {
+			t.Errorf("card %d is named %q, want %q", card.Cardvalue, card.Cardname, CardNames[card.Cardvalue-1])
+		}
+	}
+	for value := 1; value <= 7; value++ {
+		if counts[value] != 8 {
+			t.Errorf("deck has %d cards of value %d, want 8", counts[value], value)
+		}
+	}
+}
+
+func TestNextCardValue(t *testing.T) {
+	tests := []struct{ value, want int }{
+		{1, 2},
+		{5, 6},
+		{6, 7},
+		{7, 1}, // A One follows a Llama
+	}
+	for _, tt := range tests {
+		if got := NextCardValue(tt.value); got != tt.want {
+			t.Errorf("NextCardValue(%d) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestScoreHand(t *testing.T) {
+	tests := []struct {
+		hand string
+		want int
+	}{
+		{"", 0},
+		{"1", 1},
+		{"123", 6},
+		{"3333", 3}, // Each value only counts once
+		{"7", 10},   // A Llama is worth 10
+		{"77", 10},
+		{"1234567", 31},
+	}
+	for _, tt := range tests {
+		if got := ScoreHand(hand(tt.hand)); got != tt.want {
+			t.Errorf("ScoreHand(%q) = %d, want %d", tt.hand, got, tt.want)
+		}
+	}
+}
+
+func TestHandSummary(t *testing.T) {
+	if got := hand("7412").HandSummary(); got != "7412" {
+		t.Errorf("HandSummary() = %q, want %q", got, "7412")
+	}
+	if got := (Deck{}).HandSummary(); got != "" {
+		t.Errorf("empty HandSummary() = %q, want empty", got)
+	}
+}
+
+func TestSortAndRemoveDuplicates(t *testing.T) {
+	h := hand("737121")
+	h.sortHand()
+	if got := h.HandSummary(); got != "112377" {
+		t.Errorf("sorted hand = %q, want %q", got, "112377")
+	}
+	if got := h.removeDuplicateCards().HandSummary(); got != "1237" {
+		t.Errorf("hand without duplicates = %q, want %q", got, "1237")
+	}
+}
@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+)
+
+// Time controls: each move has to be made within the table's move time. A player who goes over dips into
+// their time bank (if the table gives them one), and once that is used up they get a grace extension
+// (if they have any left) before they are folded. The bank and extensions last the whole game.
+// AI players aren't held to the clock, they always move once the AI move delay is up (and the expert AI has thought about it).
+
+// How long a player has to make each move, if the table doesn't set its own move time
+const DefaultMoveTime = 60 * time.Second
+
+// How much extra time a grace extension gives, if the table doesn't say
+const DefaultExtensionTime = 15 * time.Second
+
+// MoveTime is how long a player has to make each move before their time bank is used
+func (g *Game) MoveTime() time.Duration {
+	if g.Table.MoveTime > 0 {
+		return g.Table.MoveTime
+	}
+	return DefaultMoveTime
+}
+
+// extensionTime is how much extra time each grace extension gives
+func (g *Game) extensionTime() time.Duration {
+	if g.Table.ExtensionTime > 0 {
+		return g.Table.ExtensionTime
+	}
+	return DefaultExtensionTime
+}
+
+// setUpClocks gives every player at the table the time bank and grace extensions for a new game
+func (g *Game) setUpClocks() {
+	for i := range g.Players {
+		g.Players[i].TimeBank = g.Table.TimeBank
+		g.Players[i].Extensions = g.Table.Extensions
+		g.Players[i].Extended = 0
+	}
+}
+
+// turnDeadline is when the player whose turn it is runs out of time (the turn started at StartTime)
+func (g *Game) turnDeadline(playerIndex int) time.Time {
+	player := g.Players[playerIndex]
+	return g.StartTime.Add(g.MoveTime() + player.TimeBank + player.Extended)
+}
+
+// TimeLeft is how long the player has left to make their move, 0 if it isn't their turn
+func (g *Game) TimeLeft(playerIndex int, now time.Time) time.Duration {
+	if g.Players[playerIndex].Status != STATUS_PLAYING || g.Table.Status != TABLE_PLAYING {
+		return 0
+	}
+	return max(g.turnDeadline(playerIndex).Sub(now), 0)
+}
+
+// useTurnTime takes any time the player went over the move time out of their time bank, as they finish their turn
+func (g *Game) useTurnTime(playerIndex int, now time.Time) {
+	player := &g.Players[playerIndex]
+	over := now.Sub(g.StartTime) - g.MoveTime()
+	if player.Extended > 0 {
+		player.TimeBank = 0 // They were only given an extension because their bank had run out
+	} else if over > 0 {
+		player.TimeBank = max(player.TimeBank-over, 0)
+	}
+	player.Extended = 0
+}
+
+// checkTurnClock gives the human whose turn it is a grace extension if they have run out of time,
+// or folds them if they have none left
+func (g *Game) checkTurnClock(now time.Time) []Event {
+	for i := range g.Players {
+		player := &g.Players[i]
+		if player.Status != STATUS_PLAYING || !player.Human || now.Before(g.turnDeadline(i)) {
+			continue
+		}
+		if player.Extensions > 0 {
+			player.Extensions--
+			player.Extended += g.extensionTime()
+			message := fmt.Sprintf("%s is out of time, %d more seconds", player.Name, int(g.extensionTime().Seconds()))
+			return []Event{{Type: EVENT_EXTENDED, Player: player.Name, Message: message}}
+		}
+		g.log("Turn clock ran out, folding", player.Name)
+		player.TimeBank, player.Extended = 0, 0 // All used up
+		return g.doMove(i, "F")
+	}
+	return nil
+}
@@ -0,0 +1,766 @@
+// Package engine holds the rules of the game, it knows nothing about how the players connect.
+// A Game is not safe for concurrent use, the caller has to look after its own locking.
+package engine
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Table statuses
+const (
+	TABLE_EMPTY     = 0
+	TABLE_FULL      = 1
+	TABLE_WAITING   = 2
+	TABLE_PLAYING   = 3
+	TABLE_ROUNDOVER = 4
+	TABLE_GAMEOVER  = 5
+)
+
+// Variants are the sets of rules a table can play by, "" is the same as VARIANT_STANDARD
+const VARIANT_STANDARD = "standard"
+
+var Variants = []string{VARIANT_STANDARD}
+
+type GameTable struct {
+	Table         string        `json:"t"`
+	Name          string        `json:"n"`
+	CurPlayers    int           `json:"p"` // human players
+	MaxPlayers    int           `json:"m"` // human players
+	MaxBots       int           `json:"-"` // max bots allowed (internal use)
+	BotLevel      string        `json:"-"` // difficulty of the AI players "easy" "medium" "hard" "expert" (internal use)
+	BotThinkTime  time.Duration `json:"-"` // how long the expert AI players can think about each move, 0 for the default (internal use)
+	HumansOnly    bool          `json:"-"` // no AI players are added once 2 or more humans have joined (internal use)
+	Variant       string        `json:"-"` // the rules the table plays by, see Variants (internal use)
+	WaitTime      time.Duration `json:"-"` // how long to wait for everyone to be ready before starting with AI players in the empty seats, 0 for the default (internal use)
+	MoveTime      time.Duration `json:"-"` // how long each move can take before the player's time bank is used, 0 for the default (internal use)
+	TimeBank      time.Duration `json:"-"` // extra time each player can use over the whole game when they go over the move time (internal use)
+	Extensions    int           `json:"-"` // grace extensions each player gets once their time bank has run out, before they are folded (internal use)
+	ExtensionTime time.Duration `json:"-"` // how long each grace extension is, 0 for the default (internal use)
+	Status        int           `json:"s"` // status of the table, "0=empty" "1=full" "2=waiting"  "3=playing" "4=roundover" "5=gameover"
+}
+
+type Game struct {
+	Table          GameTable
+	NumCards       int
+	Discard        Card
+	Players        Players
+	Maindeck       Deck
+	LastMovePlayed string     // Last move made by the active player (e.g., "play", "fold", "draw")
+	EndedLast      int        // The index of the player who ended the last round
+	RoundOver      bool       // Indicates if the round is over
+	Gameover       bool       // Indicates if the game is over
+	SeenCards      [8]int     // How many of each card value (1-7) have been turned up on the discard pile this round (used by the AI players)
+	StartTime      time.Time  // When the current wait (for players to join or for a move) started
+	Version        int        // Goes up by one every time something happens at the table, so clients can tell when there is a new state
+	config         GameTable  // The table as it was set up, used to put it back when the game is reset
+	rng            *rand.Rand // The table's own random numbers for shuffling, so a game can be replayed from its seed
+	logger         Logger     // Where the game writes what is going on, the log package's standard logger if nil (see log.go)
+}
+
+// Errors returned when a player can't do what they asked
+var (
+	ErrNoPlayerName   = errors.New("you need to supply a player name to join a table")
+	ErrUnknownLevel   = errors.New("not an AI level, please use easy, medium, hard or expert")
+	ErrNameTaken      = errors.New("someone is already at the table with that name")
+	ErrGameInProgress = errors.New("the table has a game in progress")
+	ErrTableFull      = errors.New("the table is full")
+	ErrNoPlayers      = errors.New("the table has no human players")
+	ErrNotHost        = errors.New("only the host can start the game")
+	ErrPlayerNotFound = errors.New("player not found at this table")
+	ErrNotYourTurn    = errors.New("it's not your turn to play")
+	ErrInvalidMove    = errors.New("that's not a valid move")
+)
+
+// EventType says what happened in an Event
+type EventType string
+
+const (
+	EVENT_JOINED    EventType = "joined"    // A player sat down at the table
+	EVENT_STARTED   EventType = "started"   // A new game was started
+	EVENT_PLAYED    EventType = "played"    // A player played a card onto the discard pile
+	EVENT_DREW      EventType = "drew"      // A player drew a card from the deck
+	EVENT_FOLDED    EventType = "folded"    // A player folded
+	EVENT_ROUNDOVER EventType = "roundover" // The round is over and the scores have been added up
+	EVENT_NEWROUND  EventType = "newround"  // The next round has been dealt
+	EVENT_GAMEOVER  EventType = "gameover"  // Someone reached 40 points and the game is over
+	EVENT_RESET     EventType = "reset"     // The table was cleared ready for a new game
+	EVENT_VIEWED    EventType = "viewed"    // A player has seen the round or game over results
+	EVENT_IDLE      EventType = "idle"      // A player stopped polling and was taken over by an AI player or removed
+	EVENT_LEFT      EventType = "left"      // A player left the table, or handed their seat to an AI player if a game was going
+	EVENT_REJOINED  EventType = "rejoined"  // A player who went idle came back and took their seat back from the AI player
+	EVENT_READY     EventType = "ready"     // A player said they are ready (or not ready) to start
+	EVENT_EXTENDED  EventType = "extended"  // A player ran out of time and was given a grace extension
+)
+
+// Event is something that happened at the table, returned by the methods that change the game
+type Event struct {
+	Type    EventType      `json:"type"`
+	Player  string         `json:"player,omitempty"`
+	Card    int            `json:"card,omitempty"`
+	Scores  []PublicPlayer `json:"scores,omitempty"` // Everyone's chips and scores, on the round over and game over events
+	Message string         `json:"msg"`
+}
+
+// NewGame sets up a new game on the table with a freshly shuffled deck
+func NewGame(table GameTable) *Game {
+	return NewSeededGame(table, time.Now().UnixNano())
+}
+
+// NewSeededGame sets up a new game on the table that shuffles the same way every time for the same seed
+func NewSeededGame(table GameTable, seed int64) *Game {
+	g := &Game{config: table, rng: rand.New(rand.NewSource(seed))}
+	g.Reset()
+	return g
+}
+
+// Reset the entire game state for the table
+func (g *Game) Reset() []Event {
+	*g = Game{
+		Table:          g.config,
+		Maindeck:       Deck{},
+		NumCards:       0,
+		Discard:        Card{},
+		Players:        Players{},
+		LastMovePlayed: "Waiting for players to join",
+		StartTime:      time.Now(),
+		EndedLast:      -1,
+		Version:        g.Version, // Keep counting up so clients waiting on the old game see the reset
+		config:         g.config,
+		rng:            g.rng,
+		logger:         g.logger,
+	}
+	g.setUpTable() // Initialize the table with a new deck and shuffle it
+	return []Event{{Type: EVENT_RESET, Message: g.LastMovePlayed}}
+}
+
+// Reconfigure changes how the table is set up. An empty table changes straight away,
+// otherwise the game carries on as it is and the new set up is used once the table is reset.
+func (g *Game) Reconfigure(table GameTable) {
+	g.config = table
+	if g.Table.Status == TABLE_EMPTY {
+		g.Table = table
+	}
+}
+
+func (g *Game) setUpTable() {
+	g.Maindeck = NewDeck() // Create a new deck for the table
+	g.shuffleDeck()        // Shuffle the deck and set the discard pile
+}
+
+// shuffleDeck shuffles the deck using the Fisher-Yates algorithm.
+// And deal out the first card to the discard pile.
+func (g *Game) shuffleDeck() {
+	for i := len(g.Maindeck) - 1; i > 0; i-- {
+		j := g.rng.Intn(i + 1)
+		g.Maindeck[i], g.Maindeck[j] = g.Maindeck[j], g.Maindeck[i]
+	}
+	g.turnUpDiscard()
+}
+
+// turnUpDiscard turns the last card in the deck up onto the discard pile, the rest of the deck is the draw pile
+func (g *Game) turnUpDiscard() {
+	g.Discard = g.Maindeck[55] // Set the discard to the last card in the deck
+	g.NumCards = 55            // Cards 0-54 are left to draw, they are drawn from the end of the deck
+	g.SeenCards = [8]int{}     // Start counting the cards seen for the new round
+	g.SeenCards[g.Discard.Cardvalue]++
+}
+
+// Join allows a player to join the table, botLevel is optional and only used by the first player to sit down.
+// The first human to sit down is the host, see StartBy and SetReady for how the game gets started.
+// The name of a player who went idle stays taken, they get their seat back by using their session token again (see Reclaim).
+func (g *Game) Join(name string, botLevel string) ([]Event, error) {
+	if name == "" {
+		return nil, ErrNoPlayerName
+	}
+	switch {
+	case botLevel != "" && AIStrategies[botLevel] == nil:
+		return nil, ErrUnknownLevel
+	case g.HasPlayer(name) || g.FindDisconnected(name) != -1: // A player who went idle only gets their seat back with their session token
+		return nil, ErrNameTaken
+	case g.Table.Status == TABLE_PLAYING || g.Table.Status == TABLE_ROUNDOVER || g.Table.Status == TABLE_GAMEOVER:
+		return nil, ErrGameInProgress
+	case g.Table.Status == TABLE_FULL:
+		return nil, ErrTableFull
+	}
+
+	g.log("Success !!.. Player ", name, " Joined table ", g.Table.Table) // Log the player joining the table
+	events := []Event{{Type: EVENT_JOINED, Player: name, Message: name + " joined table " + g.Table.Table}}
+	g.Table.Status = TABLE_WAITING // set status to waiting
+	if botLevel != "" && g.Table.CurPlayers == 0 {
+		g.Table.BotLevel = botLevel // The first player to sit at the table picks how hard the AI players are
+	}
+	g.Players = append(g.Players, Player{
+		Name:           name,
+		Human:          true,
+		Status:         STATUS_WAITING,
+		Hand:           Deck{},
+		Playorder:      g.Table.CurPlayers, // Set the play order to the current number of players
+		LastPolledTime: time.Now(),         // Set the last polled time to now
+	})
+	g.Table.CurPlayers++ // Increment the current players count
+	if g.Table.CurPlayers >= g.Table.MaxPlayers {
+		g.Table.Status = TABLE_FULL // Set the status to full if max players reached, the game starts when everyone is ready
+	}
+	g.StartTime = time.Now() // Reset the waiting timer for the game state
+	return events, nil
+}
+
+// AddBot seats an AI player at the table before the game starts, level is the AI level it plays at
+// (empty for the table's level). Used when a game needs particular AI players, like the simulator.
+func (g *Game) AddBot(name string, level string) error {
+	switch {
+	case level != "" && AIStrategies[level] == nil:
+		return ErrUnknownLevel
+	case g.HasPlayer(name):
+		return ErrNameTaken
+	case g.Table.Status >= TABLE_PLAYING:
+		return ErrGameInProgress
+	case g.Table.CurPlayers >= g.Table.MaxPlayers:
+		return ErrTableFull
+	}
+	g.Players = append(g.Players, Player{Name: name, BotLevel: level, Status: STATUS_WAITING, Hand: Deck{}, Playorder: g.Table.CurPlayers})
+	g.Table.CurPlayers++
+	return nil
+}
+
+// Leave takes the player away from the table. Before the game starts their seat is freed up,
+// once it has started an AI player takes over their seat so the game can carry on.
+// The table is reset if there are no humans left.
+func (g *Game) Leave(playerIndex int) []Event {
+	name := g.Players[playerIndex].Name
+	events := []Event{}
+	if g.Table.Status >= TABLE_PLAYING {
+		if g.Players[playerIndex].Human {
+			g.handToBot(playerIndex)
+		}
+		g.Players[playerIndex].Token, g.Players[playerIndex].ShortToken = "", "" // They are gone, so their session is over
+		events = append(events, Event{Type: EVENT_LEFT, Player: name, Message: name + " left the table, an AI player has taken over"})
+	} else {
+		g.removePlayer(playerIndex)
+		events = append(events, Event{Type: EVENT_LEFT, Player: name, Message: name + " left the table"})
+	}
+	g.log(events[0].Message, g.Table.Table)
+	return append(events, g.idleTableClose(time.Now())...)
+}
+
+// FindDisconnected finds the seat of a player who went idle and was taken over by an AI player, -1 if there isn't one
+func (g *Game) FindDisconnected(name string) int {
+	for i, player := range g.Players {
+		if player.Disconnected && player.Name == name+"-AI" {
+			return i
+		}
+	}
+	return -1
+}
+
+// Reclaim gives a player who went idle their seat back from the AI player, with the hand and chips they have now
+func (g *Game) Reclaim(playerIndex int) []Event {
+	player := &g.Players[playerIndex]
+	player.Name = strings.TrimSuffix(player.Name, "-AI")
+	player.Human = true
+	player.Disconnected = false
+	player.LastPolledTime = time.Now()
+	if player.Status == STATUS_PLAYING {
+		g.StartTime = time.Now() // Give them the full time to make their move
+	}
+	g.log(player.Name, "is back at table", g.Table.Table)
+	return []Event{{Type: EVENT_REJOINED, Player: player.Name, Message: player.Name + " is back and has taken their seat again"}}
+}
+
+// handToBot turns the player into an AI player, who carries on with their hand and chips
+func (g *Game) handToBot(playerIndex int) {
+	player := &g.Players[playerIndex]
+	player.Human = false              // Change the player to an AI player
+	player.Name = player.Name + "-AI" // Change the player name to indicate they are now an AI player
+}
+
+// removePlayer takes the player out of their seat and moves everyone after them up one place
+func (g *Game) removePlayer(playerIndex int) {
+	g.Players = append(g.Players[:playerIndex], g.Players[playerIndex+1:]...)
+	for i := range g.Players {
+		g.Players[i].Playorder = i
+	}
+	g.Table.CurPlayers--
+	if g.Table.CurPlayers < g.Table.MaxPlayers && g.Table.Status == TABLE_FULL {
+		g.Table.Status = TABLE_WAITING // There is a seat free again
+	}
+}
+
+// Check if player name is already taken
+func (g *Game) HasPlayer(name string) bool {
+	return g.FindPlayer(name) != -1
+}
+
+// find the index of a player at the table by their name
+func (g *Game) FindPlayer(name string) int {
+	for i, player := range g.Players {
+		if player.Name == name {
+			return i // Return the index of the player if found
+		}
+	}
+	return -1 // Return -1 if the player is not found
+}
+
+// find the index of the player with the session token (either the full or short form)
+func (g *Game) FindToken(token string) int {
+	if token == "" {
+		return -1
+	}
+	for i, player := range g.Players {
+		if subtle.ConstantTimeCompare([]byte(player.Token), []byte(token)) == 1 || subtle.ConstantTimeCompare([]byte(player.ShortToken), []byte(token)) == 1 {
+			return i
+		}
+	}
+	return -1
+}
+
+// Start a new game on the table, filling any empty seats with AI players
+func (g *Game) Start() ([]Event, error) {
+	switch {
+	case g.Table.CurPlayers == 0:
+		return nil, ErrNoPlayers
+	case g.Table.Status >= TABLE_PLAYING:
+		return nil, ErrGameInProgress
+	}
+
+	// fill up the empty slots with AI players if there are less than 6 players up to the maxiumum  bots allowed at that table
+	maxBots := g.Table.MaxBots
+	if g.Table.HumansOnly && g.Table.CurPlayers > 1 {
+		maxBots = 0 // No bots allowed on a humans only table once 2 or more humans have joined
+	}
+	for i := 0; i < maxBots; i++ {
+		if g.Table.CurPlayers >= g.Table.MaxPlayers {
+			break // Stop adding AI players if the maximum number of players is reached
+		}
+		// Create a new AI player
+		g.Players = append(g.Players, Player{
+			Name:      fmt.Sprintf("AI-%d", i+1),
+			Human:     false,
+			Status:    STATUS_WAITING,
+			Hand:      Deck{},
+			Playorder: g.Table.CurPlayers, // Set the play order based on the current number of players
+		})
+		g.Table.CurPlayers++
+	}
+
+	g.setUpClocks()                                                                         // Give everyone their time bank and grace extensions for the game
+	g.StartTime = time.Now()                                                                // The first player's turn starts now, not when the last player joined
+	g.Table.Status = TABLE_PLAYING                                                          // Set the table status to playing
+	g.Players[0].Status = STATUS_PLAYING                                                    // make the first player status to playing
+	g.LastMovePlayed = "Game Started, Waiting for " + g.Players[0].Name + " to make a move" // Update the last move played to indicate the game has started
+	g.dealCards()                                                                           // Deal cards to all players at the table
+	return []Event{{Type: EVENT_STARTED, Message: g.LastMovePlayed}}, nil
+}
+
+// Host returns the index of the table's host, the first human at the table (after them the next, if they leave),
+// or -1 if there are no humans at the table
+func (g *Game) Host() int {
+	for i, player := range g.Players {
+		if player.Human {
+			return i
+		}
+	}
+	return -1
+}
+
+// StartBy starts the game for the player, only the host can start it before everyone is ready
+func (g *Game) StartBy(playerIndex int) ([]Event, error) {
+	if g.Table.Status < TABLE_PLAYING && playerIndex != g.Host() {
+		return nil, ErrNotHost
+	}
+	return g.Start()
+}
+
+// SetReady says whether the player is ready to start, the game starts as soon as every human at the table is ready
+func (g *Game) SetReady(playerIndex int, ready bool) ([]Event, error) {
+	if g.Table.Status >= TABLE_PLAYING {
+		return nil, ErrGameInProgress
+	}
+	player := &g.Players[playerIndex]
+	player.Ready = ready
+	message := player.Name + " is ready"
+	if !ready {
+		message = player.Name + " is not ready"
+	}
+	events := []Event{{Type: EVENT_READY, Player: player.Name, Message: message}}
+	for _, other := range g.Players {
+		if other.Human && !other.Ready {
+			return events, nil // Still waiting for someone
+		}
+	}
+	g.log("Everyone is ready, starting new game on table", g.Table.Table)
+	started, err := g.Start()
+	return append(events, started...), err
+}
+
+// WaitTime is how long the table waits for everyone to be ready before starting the game
+func (g *Game) WaitTime() time.Duration {
+	if g.Table.WaitTime > 0 {
+		return g.Table.WaitTime
+	}
+	return DefaultWaitTime
+}
+
+// deal cards to all players
+func (g *Game) dealCards() {
+	for i := 0; i < g.Table.CurPlayers; i++ {
+		player := &g.Players[i]
+		for j := 0; j < 6; j++ {
+			g.NumCards--                                              // Decrement the number of cards in the deck
+			player.Hand = append(player.Hand, g.Maindeck[g.NumCards]) // draw the last card from the deck
+			player.NumCards++                                         // Increment the number of cards in the player's hand
+		}
+	}
+}
+
+// PlayerPolled records that a player has just asked for the game state, and works out their valid moves
+func (g *Game) PlayerPolled(playerIndex int) {
+	g.Players[playerIndex].LastPolledTime = time.Now()
+	g.Players[playerIndex].ValidMove = g.ValidMoves(playerIndex)
+}
+
+// HandSummary returns the player's hand as a string of card values
+func (g *Game) HandSummary(playerIndex int) string {
+	return g.Players[playerIndex].Hand.HandSummary()
+}
+
+// checks the player's hand and returns a string of valid moves possible for that player
+func (g *Game) ValidMoves(playerIndex int) string {
+	validMoves := ""
+
+	if g.Table.Status == TABLE_ROUNDOVER { // If the round is over, players can only view results
+		return "R" // Player can view results
+	}
+
+	if g.Table.Status == TABLE_GAMEOVER { // If the Game is over, players can only view game over re
+		return "G" // Player can view results
+	}
+
+	if g.Players[playerIndex].Status == STATUS_PLAYING {
+		// Check if any card in hand matches or is higher than discard pile
+		for _, card := range g.Players[playerIndex].Hand {
+			if card.Cardvalue == g.Discard.Cardvalue {
+				validMoves = strconv.Itoa(g.Discard.Cardvalue) // Player can play a matching card
+				break
+			}
+		}
+		nextValue := NextCardValue(g.Discard.Cardvalue)
+		for _, card := range g.Players[playerIndex].Hand {
+			if card.Cardvalue == nextValue {
+				validMoves = validMoves + strconv.Itoa(nextValue) // Player can play a matching card
+				break
+			}
+		}
+		lastone := false
+		foldedCount := 0
+
+		for _, player := range g.Players {
+			if player.Status == STATUS_FOLDED {
+				foldedCount++
+			}
+		}
+		if foldedCount == len(g.Players)-1 { // If all but one player has folded, the last player can not draw any new cards
+			lastone = true
+		}
+		if g.NumCards > 0 && !lastone {
+			validMoves = validMoves + "D" // Player can draw
+		}
+		validMoves = validMoves + "F" // Player can fold
+	}
+
+	return validMoves
+}
+
+// ApplyMove makes a move for a player after checking it is their turn and the move is valid
+// (e.g., "1"-"7" to play a card, "D" draw, "F" fold, "R" viewed the round results, "G" viewed the game over results)
+func (g *Game) ApplyMove(playerIndex int, move string) ([]Event, error) {
+	if playerIndex < 0 || playerIndex >= len(g.Players) {
+		return nil, ErrPlayerNotFound
+	}
+	player := &g.Players[playerIndex]
+	validMoves := player.ValidMove
+	if player.Status == STATUS_PLAYING {
+		validMoves = g.ValidMoves(playerIndex) // Make sure the moves are up to date, the player may not have polled since their turn started
+	} else if validMoves != "R" && validMoves != "G" {
+		return nil, ErrNotYourTurn // Only players who are viewing the results can move when it's not their turn
+	}
+	if len(move) != 1 || !strings.Contains(validMoves, move) {
+		return nil, ErrInvalidMove
+	}
+	return g.doMove(playerIndex, move), nil
+}
+
+// Perform the valid move for the player
+func (g *Game) doMove(playerIndex int, move string) []Event {
+	nextValue := NextCardValue(g.Discard.Cardvalue)
+	player := &g.Players[playerIndex]
+	events := []Event{}
+
+	if player.Status == STATUS_PLAYING {
+		g.useTurnTime(playerIndex, time.Now()) // Take any time they went over out of their time bank
+	}
+	g.StartTime = time.Now() // Reset the waiting timer
+	switch move {
+	case strconv.Itoa(g.Discard.Cardvalue): // Play card onto the discard pile
+		g.LastMovePlayed = player.Name + " played a " + g.Discard.Cardname
+		events = append(events, Event{Type: EVENT_PLAYED, Player: player.Name, Card: g.Discard.Cardvalue, Message: g.LastMovePlayed})
+		g.removeCardFromHand(playerIndex, g.Discard) // Remove the played card from the player's hand
+		g.SeenCards[g.Discard.Cardvalue]++
+	case strconv.Itoa(nextValue): // Play card onto the discard pile
+		g.LastMovePlayed = player.Name + " played a " + CardNames[nextValue-1]
+		events = append(events, Event{Type: EVENT_PLAYED, Player: player.Name, Card: nextValue, Message: g.LastMovePlayed})
+		g.Discard = Card{Cardvalue: nextValue, Cardname: CardNames[nextValue-1]}
+		g.removeCardFromHand(playerIndex, g.Discard) // Remove the played card from the player's hand
+		g.SeenCards[nextValue]++
+	case "D": // Draw
+		g.LastMovePlayed = player.Name + " drew a card from the deck"
+		events = append(events, Event{Type: EVENT_DREW, Player: player.Name, Message: g.LastMovePlayed})
+		g.addCardtohand(playerIndex) // Add a card to the player's hand
+	case "F": // Fold
+		g.LastMovePlayed = player.Name + " folded"
+		events = append(events, Event{Type: EVENT_FOLDED, Player: player.Name, Message: g.LastMovePlayed})
+		player.Status = STATUS_FOLDED
+		g.EndedLast = playerIndex
+	case "R": // Viewed the results of the round
+		player.Status = STATUS_ROUND_VIEWED
+		player.ValidMove = "G" // Set valid move to view game over results only
+		return append(events, Event{Type: EVENT_VIEWED, Player: player.Name, Message: player.Name + " viewed the results"})
+	case "G": // Viewed the gameover screens
+		player.Status = STATUS_GAMEOVER_VIEWED
+		player.ValidMove = ""
+		return append(events, Event{Type: EVENT_VIEWED, Player: player.Name, Message: player.Name + " viewed the final results"})
+	}
+
+	// Update the player's  status
+	player.ValidMove = "" // Clear the valid moves after the player has made a move
+	if player.Status == STATUS_PLAYING {
+		player.Status = STATUS_WAITING // Set the current player's status to waiting if they didn't fold
+	}
+
+	// check if the round end conditions have been met and if not find the next player to play
+	if g.checkRoundEndCondtions() {
+		g.log("Round ended for table", g.Table.Table)
+	} else {
+		// If there are still players playing, find the next player to play
+		nextPlayerIndex := playerIndex + 1
+		if nextPlayerIndex >= len(g.Players) {
+			nextPlayerIndex = 0 // Wrap around to the first player if we reach the end
+		}
+		for i := 0; i < len(g.Players); i++ {
+			if g.Players[nextPlayerIndex].Status != STATUS_FOLDED { // skip folded players
+				g.Players[nextPlayerIndex].Status = STATUS_PLAYING                   // Set the next player to playing status
+				g.Players[nextPlayerIndex].ValidMove = g.ValidMoves(nextPlayerIndex) // Set valid moves for the next player
+				break
+			}
+			nextPlayerIndex++
+			if nextPlayerIndex >= len(g.Players) {
+				nextPlayerIndex = 0 // Wrap around to the first player if we reach the end
+			}
+		}
+	}
+	return events
+}
+
+func (g *Game) removeCardFromHand(playerIndex int, card Card) {
+	player := &g.Players[playerIndex]
+	for i, c := range player.Hand {
+		if c.Cardvalue == card.Cardvalue {
+			player.Hand = append(player.Hand[:i], player.Hand[i+1:]...) // Remove the card from the player's hand
+			player.NumCards--                                           // Decrement the number of cards in hand
+			if player.NumCards <= 0 {
+				player.Status = STATUS_WON // If the player has no cards left, set their status to won
+				g.EndedLast = playerIndex
+				g.log(player.Name, "has won the round!")
+			}
+			return
+		}
+	}
+}
+
+func (g *Game) addCardtohand(playerIndex int) {
+	g.NumCards--                                                                              // Decrement the number of cards in the deck
+	g.Players[playerIndex].Hand = append(g.Players[playerIndex].Hand, g.Maindeck[g.NumCards]) // draw the last card from the deck
+	g.Players[playerIndex].NumCards++
+}
+
+// PlayAITurn makes the move for the AI player whose turn it is (if it is an AI player's turn)
+func (g *Game) PlayAITurn() []Event {
+	for i := 0; i < len(g.Players); i++ {
+		if g.Players[i].Status == STATUS_PLAYING && !g.Players[i].Human {
+			return g.doMove(i, g.aiMove(i)) // Perform the AI's move
+		}
+	}
+	return nil
+}
+
+// playQuickAITurn is PlayAITurn for the AI players that don't think about their move,
+// the expert AI's turns are left for the caller to play with NextAITurn and FinishAITurn
+func (g *Game) playQuickAITurn() []Event {
+	for i := 0; i < len(g.Players); i++ {
+		if g.Players[i].Status == STATUS_PLAYING && !g.Players[i].Human {
+			if _, thinks := g.aiStrategy(i).(MonteCarloAI); thinks {
+				return nil
+			}
+			return g.doMove(i, g.aiMove(i)) // Perform the AI's move
+		}
+	}
+	return nil
+}
+
+func (g *Game) checkRoundEndCondtions() bool {
+	// Check if all players have folded
+	foldedCount := 0
+	wonCount := 0
+	for _, player := range g.Players {
+		if player.Status == STATUS_FOLDED {
+			foldedCount++
+		}
+		if player.Status == STATUS_WON {
+			wonCount++
+		}
+	}
+	if foldedCount >= g.Table.CurPlayers || wonCount >= 1 {
+		g.LastMovePlayed = "Round over, adding up the scores"
+		return true // Round ends if all players have folded or one player has no cards left in the thier hand
+	}
+	return false // Round continues if there are still players playing and cards available
+}
+
+// RoundEnded checks if the round is over (everyone has folded or someone has gone out)
+func (g *Game) RoundEnded() bool {
+	return g.checkRoundEndCondtions()
+}
+
+// End of round scoreing
+// EndRound calculates the scores for each player at the end of the round
+func (g *Game) EndRound() []Event {
+	// Check if score has already been calculated for this round
+	if g.RoundOver {
+		g.LastMovePlayed = "Please view the results"
+		g.setEndofRoundStatus()
+		return nil
+	}
+
+	g.log("------------- End of round summary ------------------")
+	scores := []string{}
+	for i := 0; i < len(g.Players); i++ {
+		player := &g.Players[i]
+		player.Hand.sortHand()                           // Sort the player's hand before calculating the score
+		player.Hand = player.Hand.removeDuplicateCards() // Remove any duplicate cards from the player's hand before calculating the score
+
+		// Calculate the score based on the cards remaining in the player's hand
+		roundScore := 0
+		for _, card := range player.Hand {
+			if card.Cardvalue > 0 && card.Cardvalue < 7 {
+				roundScore += card.Cardvalue // Number cards score their face value (each value only counts once)
+			}
+			if card.Cardvalue == 7 {
+				roundScore += 10 // A Llama is worth 10 points
+			}
+		}
+
+		// A player who went out gets to hand back a chip, a black one if they have one otherwise a white one
+		if len(player.Hand) == 0 {
+			roundScore = -player.returnChip()
+		} else {
+			player.addChips(roundScore) // Take chips to the value of the cards left in hand
+		}
+
+		player.RoundScore = roundScore
+		g.log(player.Name, "scored", roundScore, "this round, total score", player.Score)
+		scores = append(scores, fmt.Sprintf("%s %d", player.Name, roundScore))
+	}
+
+	g.LastMovePlayed = "Please view the results"
+	g.RoundOver = true // Set the round over flag to true to prevent multiple score calculations
+	g.Table.Status = TABLE_ROUNDOVER
+	g.setEndofRoundStatus()
+	return []Event{{Type: EVENT_ROUNDOVER, Scores: g.PublicView().Players, Message: "Round over: " + strings.Join(scores, ", ")}}
+}
+
+func (g *Game) setEndofRoundStatus() {
+	for i := 0; i < len(g.Players); i++ {
+		g.Players[i].ValidMove = "R"  // Set valid moves to view results only
+		if g.Players[i].Score >= 40 { // The game is over once any player reaches 40 points
+			g.Gameover = true
+		}
+	}
+	g.sortByRoundScore()
+	g.Players[0].Status = STATUS_WON
+	g.setPlayorOrder()
+}
+
+func (g *Game) setEndofGameStatus() {
+	for i := 0; i < len(g.Players); i++ {
+		g.Players[i].ValidMove = "G" // Set valid moves to view results only
+	}
+	g.sortByFinalScore()
+	g.Players[0].Status = STATUS_WON
+}
+
+func (g *Game) sortByRoundScore() {
+	sort.SliceStable(g.Players, func(i, j int) bool {
+		return g.Players[i].RoundScore < g.Players[j].RoundScore
+	})
+}
+
+func (g *Game) sortByFinalScore() {
+	sort.SliceStable(g.Players, func(i, j int) bool {
+		return g.Players[i].Score < g.Players[j].Score
+	})
+}
+
+// Set the play order for each player based on their index in the Players slice
+func (g *Game) setPlayorOrder() {
+	sort.SliceStable(g.Players, func(i, j int) bool {
+		return g.Players[i].Playorder < g.Players[j].Playorder
+	})
+}
+
+// Check if all human players have viewed the results
+func (g *Game) allViewedResults() bool {
+	for _, player := range g.Players {
+		if player.Status != STATUS_ROUND_VIEWED && player.Human {
+			return false
+		}
+	}
+	return true
+}
+
+// Check if all human players have viewed Game Over Screen
+func (g *Game) allViewedGameOver() bool {
+	for _, player := range g.Players {
+		if player.Status != STATUS_GAMEOVER_VIEWED && player.Human {
+			return false
+		}
+	}
+	return true
+}
+
+// NextRound resets the game state and deals the next round
+func (g *Game) NextRound() []Event {
+	g.log("------------- Resetting table  ------------------")
+	g.shuffleDeck()
+	g.LastMovePlayed = "New Round, waiting for players to return to the table" // Reset the last move played message
+	g.RoundOver = false                                                        // Reset the round over flag for the next
+	g.StartTime = time.Now()                                                   // Reset the waiting timer for the gamestate
+	g.setPlayorOrder()                                                         // Set the play order for each player based on their index in the Players slice
+	// Reset the players' status and hands for the next round
+	for i := 0; i < len(g.Players); i++ {
+		g.Players[i].Status = STATUS_WAITING // Set all players status to waiting for the next round
+		g.Players[i].Hand = Deck{}           // Reset the player's hand for the next round
+		g.Players[i].NumCards = 0            // Reset the number of cards in hand for the next round
+		g.Players[i].ValidMove = ""          // Clear the valid moves for the next round
+	}
+	g.dealCards()                                  // Deal cards to all players at the table for the next round
+	g.Players[g.EndedLast].Status = STATUS_PLAYING // Set the player who ended the last round to be the player that starts the next round
+	g.Table.Status = TABLE_PLAYING
+	return []Event{{Type: EVENT_NEWROUND, Message: g.LastMovePlayed}}
+}
@@ -0,0 +1,682 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// hand makes a hand from a string of card values, e.g. "1347"
+func hand(values string) Deck {
+	h := Deck{}
+	for _, v := range values {
+		value, _ := strconv.Atoi(string(v))
+		h = append(h, Card{Cardvalue: value, Cardname: CardNames[value-1]})
+	}
+	return h
+}
+
+// stackedDeck builds a full deck that turns up the discard, deals the hands (in seat order) and then gives out the draws in order
+func stackedDeck(t *testing.T, discard int, hands []string, draws string) Deck {
+	t.Helper()
+	left := map[int]int{}
+	for value := 1; value <= 7; value++ {
+		left[value] = 8
+	}
+	top := []int{discard} // The cards in the order they come off the deck
+	for _, h := range hands {
+		if len(h) != 6 {
+			t.Fatalf("hand %q must have 6 cards", h)
+		}
+		for _, card := range hand(h) {
+			top = append(top, card.Cardvalue)
+		}
+	}
+	for _, card := range hand(draws) {
+		top = append(top, card.Cardvalue)
+	}
+
+	deck := make(Deck, 56)
+	for i, value := range top {
+		left[value]--
+		if left[value] < 0 {
+			t.Fatalf("the script uses more than 8 cards of value %d", value)
+		}
+		deck[55-i] = Card{Cardvalue: value, Cardname: CardNames[value-1]}
+	}
+	i := 0
+	for value := 1; value <= 7; value++ {
+		for ; left[value] > 0; left[value]-- {
+			deck[i] = Card{Cardvalue: value, Cardname: CardNames[value-1]}
+			i++
+		}
+	}
+	return deck
+}
+
+// scriptedGame sits human players P1, P2... at a table and starts a game dealt from a stacked deck
+func scriptedGame(t *testing.T, discard int, hands []string, draws string) *Game {
+	t.Helper()
+	g := NewSeededGame(GameTable{Table: "test", Name: "Test", MaxPlayers: 6}, 1)
+	for i := range hands {
+		if _, err := g.Join(fmt.Sprintf("P%d", i+1), ""); err != nil {
+			t.Fatalf("join: %v", err)
+		}
+	}
+	g.Maindeck = stackedDeck(t, discard, hands, draws)
+	g.turnUpDiscard()
+	if _, err := g.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	return g
+}
+
+// playingGame sets up a game part way through a round, with player 0 to play
+func playingGame(discard int, hands ...string) *Game {
+	g := NewSeededGame(GameTable{Table: "test", Name: "Test", MaxPlayers: 6}, 1)
+	for i, h := range hands {
+		g.Players = append(g.Players, Player{Name: fmt.Sprintf("P%d", i+1), Human: true, Status: STATUS_WAITING, Hand: hand(h), NumCards: len(h), Playorder: i, LastPolledTime: time.Now()})
+	}
+	g.Table.CurPlayers = len(hands)
+	g.Table.Status = TABLE_PLAYING
+	g.Players[0].Status = STATUS_PLAYING
+	g.Discard = Card{Cardvalue: discard, Cardname: CardNames[discard-1]}
+	g.NumCards = 20
+	return g
+}
+
+// turn returns the index of the player whose turn it is
+func turn(g *Game) int {
+	for i, player := range g.Players {
+		if player.Status == STATUS_PLAYING {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestSeededShuffleIsRepeatable(t *testing.T) {
+	a := NewSeededGame(GameTable{Table: "a", MaxPlayers: 6}, 42)
+	b := NewSeededGame(GameTable{Table: "b", MaxPlayers: 6}, 42)
+	c := NewSeededGame(GameTable{Table: "c", MaxPlayers: 6}, 43)
+	if a.Maindeck.HandSummary() != b.Maindeck.HandSummary() {
+		t.Error("two games with the same seed shuffled differently")
+	}
+	if a.Maindeck.HandSummary() == c.Maindeck.HandSummary() {
+		t.Error("two games with different seeds shuffled the same")
+	}
+
+	// The next round carries on from the same random numbers
+	a.Join("P1", "")
+	a.Join("P2", "")
+	b.Join("P1", "")
+	b.Join("P2", "")
+	a.Start()
+	b.Start()
+	a.EndedLast, b.EndedLast = 0, 0
+	a.NextRound()
+	b.NextRound()
+	if a.Maindeck.HandSummary() != b.Maindeck.HandSummary() {
+		t.Error("the second round shuffled differently for the same seed")
+	}
+}
+
+func TestDealUsesEveryCardOnce(t *testing.T) {
+	g := NewSeededGame(GameTable{Table: "test", MaxPlayers: 6}, 7)
+	for i := 0; i < 6; i++ {
+		g.Join(fmt.Sprintf("P%d", i+1), "")
+	}
+	if _, err := g.Start(); err != nil || g.Table.Status != TABLE_PLAYING {
+		t.Fatalf("the full table didn't start: %v, status = %d", err, g.Table.Status)
+	}
+	counts := map[int]int{g.Discard.Cardvalue: 1}
+	for _, player := range g.Players {
+		if player.NumCards != 6 || len(player.Hand) != 6 {
+			t.Errorf("%s was dealt %d cards (NumCards %d), want 6", player.Name, len(player.Hand), player.NumCards)
+		}
+		for _, card := range player.Hand {
+			counts[card.Cardvalue]++
+		}
+	}
+	for _, card := range g.Maindeck[:g.NumCards] {
+		counts[card.Cardvalue]++
+	}
+	if g.NumCards != 55-36 {
+		t.Errorf("draw pile has %d cards, want %d", g.NumCards, 55-36)
+	}
+	for value := 1; value <= 7; value++ {
+		if counts[value] != 8 {
+			t.Errorf("found %d cards of value %d between the hands, discard and draw pile, want 8", counts[value], value)
+		}
+	}
+}
+
+func TestValidMoves(t *testing.T) {
+	tests := []struct {
+		name    string
+		discard int
+		hand    string
+		drawn   int // Cards left in the draw pile
+		others  int // Other players at the table
+		folded  int // How many of them have folded
+		want    string
+	}{
+		{"play same or next", 3, "3456", 10, 1, 0, "34DF"},
+		{"play same only", 3, "3566", 10, 1, 0, "3DF"},
+		{"play next only", 3, "4", 10, 1, 0, "4DF"},
+		{"nothing to play", 3, "1156", 10, 1, 0, "DF"},
+		{"one follows a llama", 7, "12", 10, 1, 0, "1DF"},
+		{"llama on a llama", 7, "17", 10, 1, 0, "71DF"},
+		{"llama follows a six", 6, "7", 10, 1, 0, "7DF"},
+		{"empty draw pile", 3, "1", 0, 1, 0, "F"},
+		{"last player can't draw", 3, "1", 10, 2, 2, "F"},
+		{"last player can still play", 3, "34", 10, 2, 2, "34F"},
+		{"some folded can still draw", 3, "1", 10, 2, 1, "DF"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hands := []string{tt.hand}
+			for i := 0; i < tt.others; i++ {
+				hands = append(hands, "55")
+			}
+			g := playingGame(tt.discard, hands...)
+			g.NumCards = tt.drawn
+			for i := 1; i <= tt.folded; i++ {
+				g.Players[i].Status = STATUS_FOLDED
+			}
+			if got := g.ValidMoves(0); got != tt.want {
+				t.Errorf("ValidMoves() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidMovesWhenNotPlaying(t *testing.T) {
+	g := playingGame(3, "34", "45")
+	if got := g.ValidMoves(1); got != "" {
+		t.Errorf("waiting player ValidMoves() = %q, want none", got)
+	}
+	g.Table.Status = TABLE_ROUNDOVER
+	if got := g.ValidMoves(1); got != "R" {
+		t.Errorf("round over ValidMoves() = %q, want R", got)
+	}
+	g.Table.Status = TABLE_GAMEOVER
+	if got := g.ValidMoves(1); got != "G" {
+		t.Errorf("game over ValidMoves() = %q, want G", got)
+	}
+}
+
+func TestRemoveCardFromHand(t *testing.T) {
+	g := playingGame(3, "3343", "5")
+	g.removeCardFromHand(0, Card{Cardvalue: 3})
+	if got := g.HandSummary(0); got != "343" {
+		t.Errorf("hand = %q, want only one 3 removed (343)", got)
+	}
+	if g.Players[0].NumCards != 3 {
+		t.Errorf("NumCards = %d, want 3", g.Players[0].NumCards)
+	}
+	g.removeCardFromHand(0, Card{Cardvalue: 6})
+	if got := g.HandSummary(0); got != "343" {
+		t.Errorf("removing a card not in the hand changed it to %q", got)
+	}
+
+	// Playing the last card wins the round
+	g.removeCardFromHand(1, Card{Cardvalue: 5})
+	if g.Players[1].NumCards != 0 || g.Players[1].Status != STATUS_WON {
+		t.Errorf("player with no cards has NumCards %d status %d, want 0 and won", g.Players[1].NumCards, g.Players[1].Status)
+	}
+	if g.EndedLast != 1 {
+		t.Errorf("EndedLast = %d, want 1", g.EndedLast)
+	}
+}
+
+func TestTurnRotationSkipsFoldedPlayers(t *testing.T) {
+	g := playingGame(3, "11", "11", "11", "11")
+	g.Players[1].Status = STATUS_FOLDED
+	g.Players[2].Status = STATUS_FOLDED
+
+	if _, err := g.ApplyMove(0, "D"); err != nil {
+		t.Fatalf("draw: %v", err)
+	}
+	if got := turn(g); got != 3 {
+		t.Fatalf("after P1 it's P%d's turn, want P4", got+1)
+	}
+	if g.Players[3].ValidMove != "DF" {
+		t.Errorf("next player's moves = %q, want them worked out (DF)", g.Players[3].ValidMove)
+	}
+	if _, err := g.ApplyMove(3, "D"); err != nil {
+		t.Fatalf("draw: %v", err)
+	}
+	if got := turn(g); got != 0 {
+		t.Fatalf("after P4 it's P%d's turn, want it to wrap round to P1", got+1)
+	}
+	if g.Players[0].Status != STATUS_PLAYING || g.Players[3].Status != STATUS_WAITING {
+		t.Errorf("statuses = %d,%d want playing and waiting", g.Players[0].Status, g.Players[3].Status)
+	}
+}
+
+func TestApplyMoveErrors(t *testing.T) {
+	g := playingGame(3, "34", "45")
+	tests := []struct {
+		name   string
+		player int
+		move   string
+		want   error
+	}{
+		{"not their turn", 1, "D", ErrNotYourTurn},
+		{"card not in hand", 0, "5", ErrInvalidMove},
+		{"more than one move", 0, "34", ErrInvalidMove},
+		{"no move", 0, "", ErrInvalidMove},
+		{"viewing results too soon", 0, "R", ErrInvalidMove},
+		{"no such player", 5, "D", ErrPlayerNotFound},
+	}
+	for _, tt := range tests {
+		if _, err := g.ApplyMove(tt.player, tt.move); !errors.Is(err, tt.want) {
+			t.Errorf("%s: ApplyMove(%d, %q) error = %v, want %v", tt.name, tt.player, tt.move, err, tt.want)
+		}
+	}
+	if g.HandSummary(0) != "34" || turn(g) != 0 {
+		t.Error("a rejected move changed the game")
+	}
+}
+
+func TestRoundEnd(t *testing.T) {
+	g := playingGame(3, "34", "45", "56")
+	if g.RoundEnded() {
+		t.Fatal("round ended before anyone folded or went out")
+	}
+	g.Players[0].Status = STATUS_FOLDED
+	g.Players[1].Status = STATUS_FOLDED
+	if g.RoundEnded() {
+		t.Fatal("round ended with a player still in")
+	}
+	g.Players[2].Status = STATUS_FOLDED
+	if !g.RoundEnded() {
+		t.Fatal("round didn't end when everyone folded")
+	}
+
+	g = playingGame(3, "34", "45", "56")
+	g.Players[1].Status = STATUS_WON
+	if !g.RoundEnded() {
+		t.Fatal("round didn't end when a player went out")
+	}
+}
+
+func TestEndRoundScoring(t *testing.T) {
+	tests := []struct {
+		name        string
+		hand        string
+		black       int
+		white       int
+		wantRound   int
+		wantBlack   int
+		wantWhite   int
+		wantGameEnd bool
+	}{
+		{"face values", "1234", 0, 0, 10, 1, 0, false},
+		{"duplicates count once", "3333", 0, 0, 3, 0, 3, false},
+		{"llama is ten", "7", 0, 0, 10, 1, 0, false},
+		{"everything", "1234567", 0, 0, 31, 3, 1, false},
+		{"white chips exchanged", "56", 0, 9, 11, 2, 0, false},
+		{"reaching forty ends the game", "7", 3, 0, 10, 4, 0, true},
+		{"going out returns a black chip", "", 2, 3, -10, 1, 3, false},
+		{"going out returns a white chip", "", 0, 3, -1, 0, 2, false},
+		{"going out with no chips", "", 0, 0, 0, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := playingGame(3, tt.hand, "2")
+			g.Players[0].BlackChips, g.Players[0].WhiteChips = tt.black, tt.white
+			g.Players[0].Score = tt.black*10 + tt.white
+			g.Players[0].NumCards = len(tt.hand)
+			g.EndRound()
+
+			player := g.Players[g.FindPlayer("P1")]
+			if player.RoundScore != tt.wantRound {
+				t.Errorf("RoundScore = %d, want %d", player.RoundScore, tt.wantRound)
+			}
+			if player.BlackChips != tt.wantBlack || player.WhiteChips != tt.wantWhite {
+				t.Errorf("chips = %d black %d white, want %d black %d white", player.BlackChips, player.WhiteChips, tt.wantBlack, tt.wantWhite)
+			}
+			if player.Score != tt.wantBlack*10+tt.wantWhite {
+				t.Errorf("Score = %d, want it to match the chips (%d)", player.Score, tt.wantBlack*10+tt.wantWhite)
+			}
+			if g.Gameover != tt.wantGameEnd {
+				t.Errorf("Gameover = %v, want %v", g.Gameover, tt.wantGameEnd)
+			}
+		})
+	}
+}
+
+func TestEndRoundOnlyScoresOnce(t *testing.T) {
+	g := playingGame(3, "56", "2")
+	events := g.EndRound()
+	if len(events) != 1 || events[0].Type != EVENT_ROUNDOVER {
+		t.Fatalf("EndRound events = %v, want one roundover event", events)
+	}
+	if len(events[0].Scores) != len(g.Players) || events[0].Scores[0].RoundScore != g.Players[0].RoundScore {
+		t.Errorf("roundover scores = %+v, want everyone's round scores", events[0].Scores)
+	}
+	if g.Table.Status != TABLE_ROUNDOVER || !g.RoundOver {
+		t.Errorf("table status %d RoundOver %v, want round over", g.Table.Status, g.RoundOver)
+	}
+	for _, player := range g.Players {
+		if player.ValidMove != "R" {
+			t.Errorf("%s ValidMove = %q, want R", player.Name, player.ValidMove)
+		}
+	}
+	if g.Players[g.FindPlayer("P2")].Status != STATUS_WON {
+		t.Errorf("the round winner should be marked as won")
+	}
+
+	if events := g.EndRound(); len(events) != 0 {
+		t.Errorf("second EndRound returned %v, want nothing", events)
+	}
+	if score := g.Players[g.FindPlayer("P1")].Score; score != 11 {
+		t.Errorf("score after EndRound twice = %d, want 11", score)
+	}
+}
+
+func TestNextRoundStartsWithWhoeverEndedTheLastRound(t *testing.T) {
+	g := playingGame(3, "3", "45", "56")
+	if _, err := g.ApplyMove(0, "3"); err != nil {
+		t.Fatalf("play: %v", err)
+	}
+	g.EndRound()
+	for i := range g.Players {
+		g.ApplyMove(i, "R")
+	}
+	g.NextRound()
+	if g.Table.Status != TABLE_PLAYING || g.RoundOver {
+		t.Errorf("table status %d RoundOver %v, want playing", g.Table.Status, g.RoundOver)
+	}
+	if got := turn(g); got != 0 {
+		t.Errorf("P%d starts the round, want P1 who went out", got+1)
+	}
+	for _, player := range g.Players {
+		if player.NumCards != 6 {
+			t.Errorf("%s has %d cards, want 6", player.Name, player.NumCards)
+		}
+	}
+}
+
+// A scripted move is made by whoever's turn it is
+type scriptedRound struct {
+	name      string
+	discard   int
+	hands     []string
+	draws     string   // The cards drawn from the deck, in order
+	moves     []string // The moves made, in turn order
+	wantHands []string // Each player's hand at the end of the round (before scoring)
+	wantScore []int    // Each player's round score
+}
+
+func TestScriptedRounds(t *testing.T) {
+	tests := []scriptedRound{
+		{
+			name:      "everyone folds",
+			discard:   4,
+			hands:     []string{"111222", "333666"},
+			moves:     []string{"F", "F"},
+			wantHands: []string{"111222", "333666"},
+			wantScore: []int{3, 9},
+		},
+		{
+			name:      "play out of a hand",
+			discard:   1,
+			hands:     []string{"123456", "777777"},
+			moves:     []string{"1", "F", "2", "3", "4", "5", "6"},
+			wantHands: []string{"", "777777"},
+			wantScore: []int{0, 10},
+		},
+		{
+			name:      "draws come off the deck in order",
+			discard:   5,
+			hands:     []string{"111111", "222222"},
+			draws:     "67",
+			moves:     []string{"D", "D", "6", "7", "F", "F"},
+			wantHands: []string{"111111", "222222"},
+			wantScore: []int{1, 2},
+		},
+		{
+			name:      "llama wraps round to one",
+			discard:   6,
+			hands:     []string{"711111", "222222"},
+			moves:     []string{"7", "F", "1", "1", "1", "1", "1"},
+			wantHands: []string{"", "222222"},
+			wantScore: []int{0, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := scriptedGame(t, tt.discard, tt.hands, tt.draws)
+			for i, hand := range tt.hands {
+				if got := g.HandSummary(i); got != hand {
+					t.Fatalf("P%d was dealt %q, want %q", i+1, got, hand)
+				}
+			}
+			for _, move := range tt.moves {
+				player := turn(g)
+				if player == -1 {
+					t.Fatalf("round ended before move %q", move)
+				}
+				if _, err := g.ApplyMove(player, move); err != nil {
+					t.Fatalf("P%d move %q: %v (valid moves %q)", player+1, move, err, g.ValidMoves(player))
+				}
+			}
+			if !g.RoundEnded() {
+				t.Fatal("round hasn't ended")
+			}
+			for i, want := range tt.wantHands {
+				if got := g.HandSummary(i); got != want {
+					t.Errorf("P%d ends with %q, want %q", i+1, got, want)
+				}
+			}
+			g.EndRound()
+			for i, want := range tt.wantScore {
+				player := g.Players[g.FindPlayer(fmt.Sprintf("P%d", i+1))]
+				if player.RoundScore != want {
+					t.Errorf("P%d scored %d, want %d", i+1, player.RoundScore, want)
+				}
+			}
+		})
+	}
+}
+
+func TestJoinAndStart(t *testing.T) {
+	g := NewSeededGame(GameTable{Table: "test", MaxPlayers: 3, MaxBots: 1, BotLevel: AI_EASY}, 1)
+	if _, err := g.Start(); !errors.Is(err, ErrNoPlayers) {
+		t.Errorf("starting an empty table error = %v, want %v", err, ErrNoPlayers)
+	}
+	if _, err := g.Join("", ""); !errors.Is(err, ErrNoPlayerName) {
+		t.Errorf("join with no name error = %v, want %v", err, ErrNoPlayerName)
+	}
+	if _, err := g.Join("P1", "silly"); !errors.Is(err, ErrUnknownLevel) {
+		t.Errorf("join with a bad level error = %v, want %v", err, ErrUnknownLevel)
+	}
+	if _, err := g.Join("P1", AI_HARD); err != nil {
+		t.Fatalf("join: %v", err)
+	}
+	if _, err := g.Join("P1", ""); !errors.Is(err, ErrNameTaken) {
+		t.Errorf("join with a taken name error = %v, want %v", err, ErrNameTaken)
+	}
+	if g.Table.Status != TABLE_WAITING || g.Table.CurPlayers != 1 || g.Table.BotLevel != AI_HARD {
+		t.Errorf("table = %+v, want waiting with 1 player at hard", g.Table)
+	}
+	if _, err := g.Start(); err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	if len(g.Players) != 2 || g.Players[1].Human || g.Table.CurPlayers != 2 {
+		t.Errorf("start should add one AI player, players = %d", len(g.Players))
+	}
+	if _, err := g.Start(); !errors.Is(err, ErrGameInProgress) {
+		t.Errorf("starting twice error = %v, want %v", err, ErrGameInProgress)
+	}
+	if _, err := g.Join("P2", ""); !errors.Is(err, ErrGameInProgress) {
+		t.Errorf("join during a game error = %v, want %v", err, ErrGameInProgress)
+	}
+
+	g.Reset()
+	if g.Table.Status != TABLE_EMPTY || len(g.Players) != 0 || g.Table.BotLevel != AI_EASY {
+		t.Errorf("reset table = %+v, want it back as it was set up", g.Table)
+	}
+}
+
+func TestLeaveBeforeTheGameStarts(t *testing.T) {
+	g := NewSeededGame(GameTable{Table: "test", Name: "Test", MaxPlayers: 6}, 1)
+	for _, name := range []string{"A", "B", "C"} {
+		g.Join(name, "")
+	}
+	events := g.Leave(g.FindPlayer("B"))
+	if len(events) != 1 || events[0].Type != EVENT_LEFT || events[0].Player != "B" {
+		t.Fatalf("Leave events = %v, want B left", events)
+	}
+	if g.HasPlayer("B") || g.Table.CurPlayers != 2 || g.Table.Status != TABLE_WAITING {
+		t.Fatalf("after B left: players %d status %d", g.Table.CurPlayers, g.Table.Status)
+	}
+	for i, player := range g.Players {
+		if player.Playorder != i {
+			t.Errorf("%s has Playorder %d, want %d", player.Name, player.Playorder, i)
+		}
+	}
+
+	// The seat can be taken again, and the last one out resets the table
+	if _, err := g.Join("B", ""); err != nil {
+		t.Fatalf("couldn't sit back down: %v", err)
+	}
+	g.Leave(0)
+	g.Leave(0)
+	if events := g.Leave(0); !hasEvent(events, EVENT_RESET) || g.Table.Status != TABLE_EMPTY || len(g.Players) != 0 {
+		t.Errorf("last player out gave %v, status %d, %d players", events, g.Table.Status, len(g.Players))
+	}
+}
+
+func TestLeaveDuringAGame(t *testing.T) {
+	g := playingGame(3, "56", "2", "14")
+	g.Players[1].Token, g.Players[1].ShortToken = "long", "SHORT"
+	events := g.Leave(1)
+	if len(events) != 1 || events[0].Type != EVENT_LEFT {
+		t.Fatalf("Leave events = %v, want one left event", events)
+	}
+	bot := g.Players[1]
+	if bot.Human || bot.Name != "P2-AI" || bot.Hand.HandSummary() != "2" || g.Table.CurPlayers != 3 {
+		t.Errorf("seat after leaving = %+v with %d players, want an AI player with P2's hand", bot, g.Table.CurPlayers)
+	}
+	if g.FindToken("long") != -1 || g.FindToken("SHORT") != -1 {
+		t.Error("the session token still works after leaving")
+	}
+
+	// Once all the humans have gone the table is reset
+	g.Leave(0)
+	if events := g.Leave(2); !hasEvent(events, EVENT_RESET) || g.Table.Status != TABLE_EMPTY {
+		t.Errorf("last human out gave %v, status %d", events, g.Table.Status)
+	}
+}
+
+func TestHumansOnlyTables(t *testing.T) {
+	table := GameTable{Table: "cave", Name: "Cave", MaxPlayers: 6, MaxBots: 5, HumansOnly: true}
+
+	alone := NewSeededGame(table, 1)
+	alone.Join("A", "")
+	alone.Start()
+	if len(alone.Players) != 6 {
+		t.Errorf("one human got %d players, want the AI players to fill the table", len(alone.Players))
+	}
+
+	together := NewSeededGame(table, 1)
+	together.Join("A", "")
+	together.Join("B", "")
+	together.Start()
+	if len(together.Players) != 2 {
+		t.Errorf("two humans got %d players, want no AI players", len(together.Players))
+	}
+}
+
+func TestReconfigure(t *testing.T) {
+	g := NewSeededGame(GameTable{Table: "test", Name: "Test", MaxPlayers: 6, MaxBots: 1}, 1)
+	g.Reconfigure(GameTable{Table: "test", Name: "Renamed", MaxPlayers: 4, MaxBots: 2})
+	if g.Table.Name != "Renamed" || g.Table.MaxPlayers != 4 {
+		t.Errorf("empty table wasn't changed straight away: %+v", g.Table)
+	}
+
+	// A table with a game going keeps its set up until it is reset
+	g.Join("A", "")
+	g.Start()
+	g.Reconfigure(GameTable{Table: "test", Name: "Later", MaxPlayers: 6, MaxBots: 5})
+	if g.Table.Name != "Renamed" || len(g.Players) != 3 {
+		t.Errorf("game in progress was changed: %+v with %d players", g.Table, len(g.Players))
+	}
+	g.Reset()
+	if g.Table.Name != "Later" || g.Table.MaxBots != 5 {
+		t.Errorf("reset didn't pick up the new set up: %+v", g.Table)
+	}
+}
+
+func TestHostAndReadyStart(t *testing.T) {
+	g := NewSeededGame(GameTable{Table: "test", MaxPlayers: 3, MaxBots: 2}, 1)
+	g.Join("Alice", "")
+	g.Join("Bob", "")
+	g.Join("Carol", "")
+	if g.Table.Status != TABLE_FULL {
+		t.Fatalf("a full table shouldn't start until everyone is ready, status = %d", g.Table.Status)
+	}
+	if g.Host() != 0 {
+		t.Errorf("host = %d, want Alice", g.Host())
+	}
+	if _, err := g.StartBy(1); !errors.Is(err, ErrNotHost) {
+		t.Errorf("Bob starting the game = %v, want ErrNotHost", err)
+	}
+
+	// Alice leaving makes Bob the host
+	g.Leave(0)
+	if g.Host() != 0 || g.Players[0].Name != "Bob" {
+		t.Errorf("after Alice left the host is %d", g.Host())
+	}
+
+	// The game starts once every human is ready
+	g.SetReady(0, true)
+	g.SetReady(0, false)
+	g.SetReady(1, true)
+	if g.Table.Status >= TABLE_PLAYING {
+		t.Fatal("the game started before everyone was ready")
+	}
+	events, err := g.SetReady(0, true)
+	if err != nil || g.Table.Status != TABLE_PLAYING || len(events) != 2 || events[1].Type != EVENT_STARTED {
+		t.Errorf("everyone ready = %v %v, status = %d", events, err, g.Table.Status)
+	}
+	if _, err := g.SetReady(0, true); !errors.Is(err, ErrGameInProgress) {
+		t.Errorf("getting ready during the game = %v", err)
+	}
+}
+
+func TestWaitTime(t *testing.T) {
+	g := NewSeededGame(GameTable{Table: "test", MaxPlayers: 3, MaxBots: 2, WaitTime: 10 * time.Second}, 1)
+	g.Join("Alice", "")
+	g.Tick(g.StartTime.Add(9 * time.Second))
+	if g.Table.Status != TABLE_WAITING {
+		t.Fatalf("started before the wait time, status = %d", g.Table.Status)
+	}
+	g.Tick(g.StartTime.Add(10 * time.Second))
+	if g.Table.Status != TABLE_PLAYING || g.Table.CurPlayers != 3 {
+		t.Errorf("after the wait time status = %d with %d players", g.Table.Status, g.Table.CurPlayers)
+	}
+	if (&Game{}).WaitTime() != DefaultWaitTime {
+		t.Errorf("a table without a wait time waits %v", (&Game{}).WaitTime())
+	}
+}
+
+func TestHostStartAfterALongWait(t *testing.T) {
+	g := NewSeededGame(GameTable{Table: "test", MaxPlayers: 3, WaitTime: 5 * time.Minute}, 1)
+	g.Join("A", "")
+	g.Join("B", "")
+	g.StartTime = time.Now().Add(-2 * time.Minute) // The last player joined 2 minutes ago
+
+	if _, err := g.StartBy(0); err != nil {
+		t.Fatal(err)
+	}
+	if events := g.Tick(time.Now().Add(time.Second)); hasEvent(events, EVENT_FOLDED) || g.Players[0].Status != STATUS_PLAYING {
+		t.Errorf("the first player was folded straight after the host started the game, events %v", events)
+	}
+}
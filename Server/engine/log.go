@@ -0,0 +1,28 @@
+package engine
+
+import (
+	"io"
+	"log"
+)
+
+// Logger is where a game writes what is going on at the table, a *log.Logger will do
+type Logger interface {
+	Println(v ...any)
+}
+
+// NoLogger throws the game's log away, EG: for the simulator which only wants its report
+var NoLogger Logger = log.New(io.Discard, "", 0)
+
+// SetLogger changes where the game writes its log, new games write to the log package's standard logger
+func (g *Game) SetLogger(logger Logger) {
+	g.logger = logger
+}
+
+// log writes a line to the game's log
+func (g *Game) log(v ...any) {
+	if g.logger == nil {
+		log.Println(v...) // A game that was never given a logger
+		return
+	}
+	g.logger.Println(v...)
+}
@@ -0,0 +1,28 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// recordingLogger keeps every line the game logs
+type recordingLogger struct {
+	lines []string
+}
+
+func (logger *recordingLogger) Println(v ...any) {
+	logger.lines = append(logger.lines, strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+}
+
+func TestGameLogsToItsLogger(t *testing.T) {
+	logger := &recordingLogger{}
+	g := NewSeededGame(GameTable{Table: "test", MaxPlayers: 6}, 1)
+	g.SetLogger(logger)
+	g.Join("P1", "")
+	g.Reset() // The logger stays with the table
+	g.Join("P2", "")
+	if len(logger.lines) != 2 || !strings.Contains(logger.lines[0], "P1") || !strings.Contains(logger.lines[1], "P2") {
+		t.Errorf("logged %q, want P1 and then P2 joining", logger.lines)
+	}
+}
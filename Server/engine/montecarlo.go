@@ -0,0 +1,225 @@
+package engine
+
+import (
+	"math/rand"
+	"strconv"
+	"time"
+)
+
+// How long the expert AI thinks about a move when the table doesn't say, and the most it is ever allowed.
+// It starts thinking once the AI move delay is up, so its turn takes the delay plus the think time (3.5 seconds at most)
+const (
+	defaultAIThinkTime = 500 * time.Millisecond
+	maxAIThinkTime     = 1500 * time.Millisecond
+)
+
+// MaxAITurnTime is the longest an AI player takes over its turn: the AI move delay and then the expert AI's thinking
+const MaxAITurnTime = AIMoveDelay + maxAIThinkTime
+
+// The fewest and most rollouts the expert AI plays out for each move it is considering
+const (
+	minRolloutsPerMove = 20
+	maxRolloutsPerMove = 2000
+)
+
+// MonteCarloAI deals out the cards it hasn't seen in lots of different ways and plays each deal
+// to the end of the round, then makes whichever move (play, draw or fold) scored best on average
+type MonteCarloAI struct {
+	ThinkTime time.Duration // How long it can spend playing out rounds before it has to move
+}
+
+// simPlayer is a player in a rollout, hands are kept as a count of each card value (1-7)
+type simPlayer struct {
+	hand   [8]int
+	cards  int
+	folded bool
+}
+
+func (ai MonteCarloAI) ChooseMove(view AIView) string {
+	moves := []string{}
+	for _, move := range view.ValidMoves {
+		moves = append(moves, string(move))
+	}
+	if len(moves) == 1 {
+		return moves[0] // Nothing to think about
+	}
+
+	deadline := time.Now().Add(ai.thinkTime())
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+
+	// Play out rounds for each move in turn until we run out of time (or have played plenty)
+	totals := make([]int, len(moves))
+	rollouts := 0
+	for rollouts < maxRolloutsPerMove && (rollouts < minRolloutsPerMove || time.Now().Before(deadline)) {
+		for i, move := range moves {
+			totals[i] += rollout(view, move, rng)
+		}
+		rollouts++
+	}
+
+	// Lower scores are better, so pick the move with the lowest total
+	best := 0
+	for i := range moves {
+		if totals[i] < totals[best] {
+			best = i
+		}
+	}
+	return moves[best]
+}
+
+// thinkTime is how long the AI spends on a move, the default if it hasn't been set and never more than the cap
+func (ai MonteCarloAI) thinkTime() time.Duration {
+	if ai.ThinkTime <= 0 {
+		return defaultAIThinkTime
+	}
+	return min(ai.ThinkTime, maxAIThinkTime)
+}
+
+// rollout deals the unseen cards at random, makes the move for the AI player, then plays the rest of the round
+// out with every player using a simple strategy, and returns the score the AI player ended the round with
+func rollout(view AIView, move string, rng *rand.Rand) int {
+	// Everything we haven't seen is either in an opponent's hand or still in the draw deck
+	pool := []int{}
+	for value, count := range unseenCards(view) {
+		for i := 0; i < count; i++ {
+			pool = append(pool, value)
+		}
+	}
+	rng.Shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+
+	// Player 0 is the AI player, the opponents follow in turn order
+	players := make([]simPlayer, len(view.Opponents)+1)
+	for _, card := range view.Hand {
+		players[0].hand[card.Cardvalue]++
+		players[0].cards++
+	}
+	for i, opponent := range view.Opponents {
+		players[i+1].folded = opponent.Status == STATUS_FOLDED
+		for j := 0; j < opponent.NumCards && len(pool) > 0; j++ {
+			players[i+1].hand[pool[0]]++
+			players[i+1].cards++
+			pool = pool[1:]
+		}
+	}
+	deck := pool
+	if len(deck) > view.DrawDeck {
+		deck = deck[:view.DrawDeck]
+	}
+	discard := view.Discard.Cardvalue
+
+	// Make the move we are testing, then let everyone play until the round ends
+	turn := 0
+	for moves := 0; moves < 500; moves++ {
+		if !players[turn].folded {
+			if turn == 0 && moves == 0 {
+				discard, deck = applySimMove(players, turn, move, discard, deck)
+			} else {
+				discard, deck = applySimMove(players, turn, simStrategy(players, turn, discard, len(deck)), discard, deck)
+			}
+			if players[turn].cards == 0 {
+				break // Someone went out
+			}
+		}
+		if allSimFolded(players) {
+			break
+		}
+		turn = (turn + 1) % len(players)
+	}
+
+	// Work out our score for the round, going out hands back a chip
+	if players[0].cards == 0 {
+		switch {
+		case view.Score >= 10:
+			return -10
+		case view.Score > 0:
+			return -1
+		}
+		return 0
+	}
+	score := 0
+	for value := 1; value <= 7; value++ {
+		if players[0].hand[value] > 0 {
+			score += simCardScore(value)
+		}
+	}
+	return score
+}
+
+// applySimMove makes a move for a player in a rollout, returning the new discard value and draw deck
+func applySimMove(players []simPlayer, turn int, move string, discard int, deck []int) (int, []int) {
+	switch move {
+	case "D":
+		if len(deck) > 0 {
+			players[turn].hand[deck[0]]++
+			players[turn].cards++
+			deck = deck[1:]
+		}
+	case "F":
+		players[turn].folded = true
+	default:
+		value, err := strconv.Atoi(move)
+		if err == nil && players[turn].hand[value] > 0 {
+			players[turn].hand[value]--
+			players[turn].cards--
+			discard = value
+		}
+	}
+	return discard, deck
+}
+
+// simStrategy is the quick strategy every player uses in a rollout (much the same as the medium AI)
+func simStrategy(players []simPlayer, turn int, discard int, deckSize int) string {
+	hand := players[turn].hand
+	best, bestSaving := 0, -1
+	for _, value := range []int{discard, NextCardValue(discard)} {
+		if hand[value] == 0 {
+			continue
+		}
+		saving := 0
+		if hand[value] == 1 {
+			saving = simCardScore(value) // Playing the last card of a value takes it off our score
+		}
+		if saving > bestSaving {
+			best, bestSaving = value, saving
+		}
+	}
+	if best != 0 {
+		return strconv.Itoa(best)
+	}
+
+	// The last player left in the round can't draw
+	othersIn := false
+	for i := range players {
+		if i != turn && !players[i].folded {
+			othersIn = true
+		}
+	}
+	score := 0
+	for value := 1; value <= 7; value++ {
+		if hand[value] > 0 {
+			score += simCardScore(value)
+		}
+	}
+	if deckSize == 0 || !othersIn || score <= 5 || (deckSize < 8 && score <= 12) {
+		return "F"
+	}
+	return "D"
+}
+
+// allSimFolded checks if every player in a rollout has folded
+func allSimFolded(players []simPlayer) bool {
+	for _, player := range players {
+		if !player.folded {
+			return false
+		}
+	}
+	return true
+}
+
+// simCardScore is what a card value is worth at the end of a round
+func simCardScore(value int) int {
+	if value == 7 {
+		return 10
+	}
+	return value
+}
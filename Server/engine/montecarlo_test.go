@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMonteCarloThinkTime(t *testing.T) {
+	tests := []struct {
+		set  time.Duration
+		want time.Duration
+	}{
+		{0, 500 * time.Millisecond}, // The default when the table doesn't say
+		{-time.Second, 500 * time.Millisecond},
+		{10 * time.Millisecond, 10 * time.Millisecond},
+		{1500 * time.Millisecond, 1500 * time.Millisecond},
+		{5 * time.Second, 1500 * time.Millisecond}, // Capped so it always moves inside the AI move delay
+	}
+	for _, tt := range tests {
+		if got := (MonteCarloAI{ThinkTime: tt.set}).thinkTime(); got != tt.want {
+			t.Errorf("think time set to %v = %v, want %v", tt.set, got, tt.want)
+		}
+	}
+}
+
+func TestMonteCarloOnlyMakesValidMoves(t *testing.T) {
+	for seed := int64(1); seed <= 3; seed++ {
+		playRound(t, MonteCarloAI{ThinkTime: time.Millisecond}, seed)
+	}
+}
+
+func TestMonteCarloMovesInTime(t *testing.T) {
+	ai := MonteCarloAI{ThinkTime: 20 * time.Millisecond}
+	view := aiView(3, "34", "55")
+	start := time.Now()
+	move := ai.ChooseMove(view)
+	if took := time.Since(start); took > 500*time.Millisecond {
+		t.Errorf("thinking for 20ms took %v", took)
+	}
+	if len(move) != 1 || !strings.Contains(view.ValidMoves, move) {
+		t.Errorf("chose %q from %q", move, view.ValidMoves)
+	}
+
+	// With only one move there is nothing to think about
+	start = time.Now()
+	if move := ai.ChooseMove(AIView{ValidMoves: "F"}); move != "F" || time.Since(start) > 100*time.Millisecond {
+		t.Errorf("with only a fold to make it chose %q in %v", move, time.Since(start))
+	}
+}
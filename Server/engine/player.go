@@ -0,0 +1,68 @@
+package engine
+
+import "time"
+
+type Status int
+
+const (
+	STATUS_WAITING         Status = 0
+	STATUS_PLAYING         Status = 1
+	STATUS_FOLDED          Status = 2
+	STATUS_WON             Status = 3 // Player has won the round
+	STATUS_ROUND_VIEWED    Status = 4 // Player has the results of the round
+	STATUS_GAMEOVER_VIEWED Status = 5 // Player has the results of the end of game
+)
+
+type Player struct {
+	Name           string
+	Human          bool
+	BotLevel       string // The AI level for this player, if empty the table's level is used
+	Status         Status
+	Score          int
+	WhiteChips     int // White chips are worth 1 point each
+	BlackChips     int // Black chips are worth 10 points each
+	Hand           Deck
+	NumCards       int           // Number of cards in hand
+	ValidMove      string        // List of valid moves for the player (e.g., "play", "fold", "draw")
+	Playorder      int           // The order in which the player plays (0 is first)
+	RoundScore     int           // Score for the current round
+	LastPolledTime time.Time     // The time when the player last called the get state function
+	Handsumary     string        // store the hand summary form for sending via JSON to 8 bit computers the
+	Token          string        // The player's session token, handed out when they join (empty for AI players)
+	ShortToken     string        // A short form of the session token that fits in an 8 bit computer's string
+	Disconnected   bool          // A human who went idle and was taken over by an AI player, they can reclaim their seat
+	Ready          bool          // The player is ready for the game to start
+	TimeBank       time.Duration // Extra time the player has left to use this game when they go over the move time
+	Extensions     int           // Grace extensions the player has left this game, used once their time bank runs out
+	Extended       time.Duration // Extra time the player has been given by grace extensions on this turn
+}
+
+// Players represents a the players at a table
+type Players []Player
+
+// Give the player chips worth the points scored, exchanging every ten white chips for a black chip
+func (player *Player) addChips(points int) {
+	player.BlackChips += points / 10
+	player.WhiteChips += points % 10
+	if player.WhiteChips >= 10 {
+		player.WhiteChips -= 10 // Exchange ten white chips for a black chip
+		player.BlackChips++
+	}
+	player.Score = player.BlackChips*10 + player.WhiteChips
+}
+
+// Hand back one chip for a player who went out, a black chip is returned before a white chip
+// Returns the value of the chip returned (0 if the player has no chips)
+func (player *Player) returnChip() int {
+	returned := 0
+	switch {
+	case player.BlackChips > 0:
+		player.BlackChips--
+		returned = 10
+	case player.WhiteChips > 0:
+		player.WhiteChips--
+		returned = 1
+	}
+	player.Score = player.BlackChips*10 + player.WhiteChips
+	return returned
+}
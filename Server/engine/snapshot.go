@@ -0,0 +1,47 @@
+package engine
+
+import (
+	"encoding/json"
+	"math/rand"
+	"time"
+)
+
+// snapshot is how a game is saved, the table's bot settings aren't in the table's JSON so they are saved alongside it
+type snapshot struct {
+	*Game
+	MaxBots  int    `json:"maxBots"`
+	BotLevel string `json:"botLevel"`
+}
+
+// Snapshot saves the whole game as JSON so it can be restored later with RestoreGame
+func (g *Game) Snapshot() ([]byte, error) {
+	return json.Marshal(snapshot{Game: g, MaxBots: g.Table.MaxBots, BotLevel: g.Table.BotLevel})
+}
+
+// RestoreGame puts a game saved with Snapshot back on the table.
+// The players' idle timers start again from now, so nobody is folded or taken over by an AI player
+// just because the server was down for a while.
+func RestoreGame(table GameTable, data []byte) (*Game, error) {
+	g := &Game{config: table, rng: rand.New(rand.NewSource(time.Now().UnixNano()))}
+	saved := snapshot{Game: g}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		return nil, err
+	}
+	g.Table.MaxBots = saved.MaxBots
+	g.Table.BotLevel = saved.BotLevel
+	g.Table.BotThinkTime = table.BotThinkTime
+	g.Table.HumansOnly = table.HumansOnly
+	g.Table.Variant = table.Variant
+	g.Table.WaitTime = table.WaitTime
+	g.Table.MoveTime = table.MoveTime
+	g.Table.TimeBank = table.TimeBank
+	g.Table.Extensions = table.Extensions
+	g.Table.ExtensionTime = table.ExtensionTime
+
+	now := time.Now()
+	g.StartTime = now
+	for i := range g.Players {
+		g.Players[i].LastPolledTime = now
+	}
+	return g, nil
+}
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	table := GameTable{Table: "test", Name: "Test", MaxPlayers: 6, MaxBots: 2, BotLevel: AI_EASY, BotThinkTime: time.Second}
+	g := NewSeededGame(table, 1)
+	g.Join("P1", AI_HARD)
+	g.Start()
+	g.ApplyMove(0, "D")
+	g.Players[0].LastPolledTime = time.Now().Add(-time.Hour)
+
+	data, err := g.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %v", err)
+	}
+	restored, err := RestoreGame(table, data)
+	if err != nil {
+		t.Fatalf("RestoreGame: %v", err)
+	}
+
+	if restored.Table != g.Table {
+		t.Errorf("restored table = %+v, want %+v", restored.Table, g.Table)
+	}
+	if restored.Maindeck.HandSummary() != g.Maindeck.HandSummary() || restored.NumCards != g.NumCards || restored.Discard != g.Discard {
+		t.Error("restored deck doesn't match")
+	}
+	if len(restored.Players) != len(g.Players) {
+		t.Fatalf("restored %d players, want %d", len(restored.Players), len(g.Players))
+	}
+	for i, player := range restored.Players {
+		if player.Name != g.Players[i].Name || player.Status != g.Players[i].Status || player.Hand.HandSummary() != g.HandSummary(i) {
+			t.Errorf("restored player %+v, want %+v", player, g.Players[i])
+		}
+	}
+	if restored.SeenCards != g.SeenCards || restored.LastMovePlayed != g.LastMovePlayed {
+		t.Error("restored round doesn't match")
+	}
+	if time.Since(restored.Players[0].LastPolledTime) > time.Minute {
+		t.Error("restored players should get a fresh idle timer")
+	}
+
+	// The restored game carries on, and goes back to the table's own settings when it is reset
+	if _, err := restored.ApplyMove(turn(restored), "F"); err != nil {
+		t.Errorf("move after restore: %v", err)
+	}
+	restored.Reset()
+	if restored.Table.BotLevel != AI_EASY {
+		t.Errorf("reset restored table level = %q, want %q", restored.Table.BotLevel, AI_EASY)
+	}
+}
+
+func TestRestoreBadSnapshot(t *testing.T) {
+	if _, err := RestoreGame(GameTable{Table: "test"}, []byte("{not json")); err == nil {
+		t.Error("RestoreGame accepted a broken snapshot")
+	}
+}
@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"time"
+)
+
+// How long an AI player waits before making its move, so the humans can see what is going on
+const AIMoveDelay = 2 * time.Second
+
+// How long a table waits for everyone to be ready before starting the game, if the table doesn't set its own wait time
+const DefaultWaitTime = 45 * time.Second
+
+// Tick runs the table's timers and AI players, it should be called regularly (see the server's game loop)
+// so games keep moving at the same pace no matter how often (or if) the clients poll
+func (g *Game) Tick(now time.Time) []Event {
+	events := []Event{}
+	elapsed := now.Sub(g.StartTime)
+
+	// If the table is waiting for players and the waiting timer has run out, start the game
+	if elapsed >= g.WaitTime() && (g.Table.Status == TABLE_WAITING || g.Table.Status == TABLE_FULL) {
+		g.StartTime = now // Reset the waiting timer
+		elapsed = 0
+		g.log("Waiting timer exceeded, starting new game")
+		started, _ := g.Start()
+		events = append(events, started...)
+	}
+	// If the table is playing and the AI move delay is up, make an AI move if it's an AI player's turn
+	// (the expert AI takes a while to think, so its moves are made with NextAITurn and FinishAITurn outside the table lock)
+	if elapsed >= AIMoveDelay && g.Table.Status == TABLE_PLAYING {
+		events = append(events, g.playQuickAITurn()...)
+	}
+
+	// If the player whose turn it is has run out of time, give them a grace extension or fold them (see clock.go)
+	if g.Table.Status == TABLE_PLAYING {
+		events = append(events, g.checkTurnClock(now)...)
+	}
+
+	// Check if the round has ended and handle the end of the round logic (only once a game has started)
+	if g.Table.Status >= TABLE_PLAYING && g.checkRoundEndCondtions() {
+		if !g.RoundOver {
+			g.log("Round ended for table", g.Table.Table)
+		}
+		events = append(events, g.EndRound()...) // Call the end of round scoring function
+	}
+
+	// check if all players have viewed the results and reset the game state if so
+	if g.allViewedResults() && g.RoundOver {
+		if g.Gameover {
+			g.setEndofGameStatus()
+			g.log("All players have viewed the results, Sorting for gameover", g.Table.Table)
+			g.Table.Status = TABLE_GAMEOVER // Set the table status to game over
+			events = append(events, Event{Type: EVENT_GAMEOVER, Player: g.Players[0].Name, Scores: g.PublicView().Players, Message: g.Players[0].Name + " won the game"})
+		} else {
+			g.log("All players have viewed the results, resetting game for table", g.Table.Table)
+			events = append(events, g.NextRound()...) // Reset the game state for a new round
+		}
+	}
+
+	// check if all players have viewed the results and reset the game state if so
+	if g.allViewedGameOver() && g.Gameover {
+		g.log("All players have viewed the final results, resetting game for table", g.Table.Table)
+		g.log("-------------Game Over Man !!  ------------------")
+		events = append(events, g.Reset()...) // Reset the game state for a new game
+	}
+
+	// Look after any players who have stopped polling, and close the table if no humans are left
+	events = append(events, g.idlePlayerChange(now)...)
+	events = append(events, g.idlePlayerRemoval(now)...)
+	if g.Table.Status != TABLE_EMPTY {
+		events = append(events, g.idleTableClose(now)...)
+	}
+	return events
+}
+
+// check if any human players have disconnected ? (IE not polled the game state for over 3 minutes) and turn them into AI players
+func (g *Game) idlePlayerChange(now time.Time) []Event {
+	events := []Event{}
+	for i := 0; i < len(g.Players); i++ {
+		player := &g.Players[i]
+		if now.Sub(player.LastPolledTime) > 3*time.Minute && player.Human {
+			events = append(events, Event{Type: EVENT_IDLE, Player: player.Name, Message: player.Name + " has gone idle, an AI player has taken over"})
+			g.handToBot(i)
+			player.Disconnected = true // Keep the seat for them in case they come back
+		}
+	}
+	return events
+}
+
+// check if any human players have disconnected (IE not polled the game state for over 5 minutes) and remove them from the table
+func (g *Game) idlePlayerRemoval(now time.Time) []Event {
+	events := []Event{}
+	for i := 0; i < len(g.Players); i++ {
+		player := &g.Players[i]
+		if now.Sub(player.LastPolledTime) > 5*time.Minute && player.Human {
+			events = append(events, Event{Type: EVENT_IDLE, Player: player.Name, Message: player.Name + " has gone idle and left the table"})
+			g.removePlayer(i) // Remove the player from the table
+			i--               // Adjust index after removal
+		}
+	}
+	return events
+}
+
+// check if any human players are at the table and if not reset the game state.
+// The seat of a player who went idle is kept for them until they would have been removed (5 minutes),
+// so someone playing on their own against the AI players can still come back and reclaim it
+func (g *Game) idleTableClose(now time.Time) []Event {
+	for i := 0; i < len(g.Players); i++ {
+		if g.Players[i].Human {
+			return nil // Exit the function if a human player is found
+		}
+		if g.Players[i].Disconnected && now.Sub(g.Players[i].LastPolledTime) <= 5*time.Minute {
+			return nil // Still holding their seat for them
+		}
+	}
+	// If no human players are found, reset the table
+	g.log("-------------Game Over Man !!  ------------------")
+	events := g.Reset() // Reset the game state for a new game
+	g.log("No human players at table, resetting game for table", g.Table.Table)
+	return events
+}
@@ -0,0 +1,311 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// hasEvent checks if an event of the given type is in the list
+func hasEvent(events []Event, eventType EventType) bool {
+	for _, event := range events {
+		if event.Type == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func TestTickStartsWaitingTable(t *testing.T) {
+	g := NewSeededGame(GameTable{Table: "test", MaxPlayers: 6, MaxBots: 2}, 1)
+	g.Join("P1", "")
+	now := g.StartTime
+
+	if events := g.Tick(now.Add(44 * time.Second)); hasEvent(events, EVENT_STARTED) || g.Table.Status != TABLE_WAITING {
+		t.Fatalf("table started before the 45 second wait was up")
+	}
+	g.PlayerPolled(0) // Keep the player from going idle
+	events := g.Tick(now.Add(45 * time.Second))
+	if !hasEvent(events, EVENT_STARTED) || g.Table.Status != TABLE_PLAYING {
+		t.Fatalf("table didn't start after 45 seconds, status %d", g.Table.Status)
+	}
+	if len(g.Players) != 3 {
+		t.Errorf("table started with %d players, want the human and 2 AI players", len(g.Players))
+	}
+}
+
+func TestTickMakesAIMoves(t *testing.T) {
+	g := playingGame(3, "55", "34", "66")
+	g.Players[1].Human = false
+	g.Players[0].Status = STATUS_WAITING
+	g.Players[1].Status = STATUS_PLAYING
+	now := time.Now()
+	g.StartTime = now
+
+	if events := g.Tick(now.Add(time.Second)); len(events) != 0 {
+		t.Fatalf("AI player moved before 2 seconds, events %v", events)
+	}
+	events := g.Tick(now.Add(2 * time.Second))
+	if len(events) != 1 || events[0].Player != "P2" {
+		t.Fatalf("AI player didn't move after 2 seconds, events %v", events)
+	}
+	if g.Players[1].Status == STATUS_PLAYING || g.Players[2].Status != STATUS_PLAYING {
+		t.Errorf("turn didn't pass on from the AI player")
+	}
+
+	// It's a human's turn now, the game loop leaves them alone
+	if events := g.Tick(g.StartTime.Add(2 * time.Second)); len(events) != 0 {
+		t.Errorf("game loop moved for a human player, events %v", events)
+	}
+}
+
+func TestExpertAITurnsAreTakenOutOfTick(t *testing.T) {
+	g := playingGame(3, "55", "34", "66")
+	g.Table.BotLevel = AI_EXPERT
+	g.Table.BotThinkTime = 10 * time.Millisecond
+	g.Players[1].Human = false
+	g.Players[0].Status = STATUS_WAITING
+	g.Players[1].Status = STATUS_PLAYING
+	now := time.Now()
+	g.StartTime = now
+
+	// Tick leaves the expert AI's move to whoever takes its turn out
+	if events := g.Tick(now.Add(2 * time.Second)); len(events) != 0 {
+		t.Fatalf("Tick moved for the expert AI player, events %v", events)
+	}
+	if _, ok := g.NextAITurn(now.Add(time.Second)); ok {
+		t.Fatalf("the expert AI's turn was taken out before 2 seconds")
+	}
+	aiTurn, ok := g.NextAITurn(now.Add(2 * time.Second))
+	if !ok || aiTurn.Player != 1 || aiTurn.View.ValidMoves != g.Players[1].ValidMove {
+		t.Fatalf("NextAITurn = %+v, %v", aiTurn, ok)
+	}
+
+	// Something happened while the AI was thinking, so its move is dropped
+	move := aiTurn.Choose()
+	g.Version++
+	if events := g.FinishAITurn(aiTurn, move); len(events) != 0 || turn(g) != 1 {
+		t.Fatalf("the AI moved after the game changed, events %v", events)
+	}
+
+	aiTurn, _ = g.NextAITurn(now.Add(2 * time.Second))
+	events := g.FinishAITurn(aiTurn, aiTurn.Choose())
+	if len(events) != 1 || events[0].Player != "P2" || turn(g) != 2 {
+		t.Errorf("the AI's move wasn't made, events %v", events)
+	}
+}
+
+func TestAIPlayersArentHeldToTheClock(t *testing.T) {
+	g := playingGame(3, "55", "34", "66")
+	g.Table.BotLevel = AI_EXPERT
+	g.Table.MoveTime = AIMoveDelay // As short as a table can set it
+	g.Players[1].Human = false
+	g.Players[0].Status = STATUS_WAITING
+	g.Players[1].Status = STATUS_PLAYING
+	now := time.Now()
+	g.StartTime = now
+
+	// The expert AI is still thinking well after the move time is up, it isn't folded for it
+	if events := g.Tick(now.Add(4 * time.Second)); len(events) != 0 || g.Players[1].Status != STATUS_PLAYING {
+		t.Fatalf("the AI player was timed out, events %v", events)
+	}
+	aiTurn, _ := g.NextAITurn(now.Add(4 * time.Second))
+	if events := g.FinishAITurn(aiTurn, "4"); len(events) != 1 || hasEvent(events, EVENT_FOLDED) || turn(g) != 2 {
+		t.Errorf("the AI player's move wasn't made, events %v", events)
+	}
+}
+
+func TestTickFoldsSlowPlayers(t *testing.T) {
+	g := playingGame(3, "55", "66")
+	now := time.Now()
+	g.StartTime = now
+
+	events := g.Tick(now.Add(60 * time.Second))
+	if !hasEvent(events, EVENT_FOLDED) || g.Players[0].Status != STATUS_FOLDED {
+		t.Fatalf("player wasn't folded after 60 seconds, events %v", events)
+	}
+	if g.Players[1].Status != STATUS_PLAYING {
+		t.Errorf("turn didn't pass on after the fold")
+	}
+}
+
+func TestTickEndsRoundsAndGames(t *testing.T) {
+	g := playingGame(3, "3", "77")
+	g.Players[1].BlackChips, g.Players[1].Score = 3, 30
+	now := time.Now()
+	g.ApplyMove(0, "3")
+
+	events := g.Tick(now)
+	if !hasEvent(events, EVENT_ROUNDOVER) || g.Table.Status != TABLE_ROUNDOVER {
+		t.Fatalf("round wasn't scored, events %v", events)
+	}
+	if !g.Gameover {
+		t.Fatal("P2 reached 40 points, the game should be over")
+	}
+
+	// Once everyone has seen the results the game over screen is next
+	g.ApplyMove(0, "R")
+	g.ApplyMove(1, "R")
+	events = g.Tick(now)
+	if !hasEvent(events, EVENT_GAMEOVER) || g.Table.Status != TABLE_GAMEOVER {
+		t.Fatalf("game over wasn't shown, events %v", events)
+	}
+	if g.Players[0].Name != "P1" || g.Players[0].ValidMove != "G" {
+		t.Errorf("the lowest score should be first with G to move, got %s %q", g.Players[0].Name, g.Players[0].ValidMove)
+	}
+
+	// And once everyone has seen that the table is cleared
+	g.ApplyMove(0, "G")
+	g.ApplyMove(1, "G")
+	events = g.Tick(now)
+	if !hasEvent(events, EVENT_RESET) || g.Table.Status != TABLE_EMPTY || len(g.Players) != 0 {
+		t.Fatalf("table wasn't reset, events %v", events)
+	}
+}
+
+func TestTickDealsNextRound(t *testing.T) {
+	g := playingGame(3, "3", "77")
+	now := time.Now()
+	g.ApplyMove(0, "3")
+	g.Tick(now)
+	g.ApplyMove(0, "R")
+	if events := g.Tick(now); hasEvent(events, EVENT_NEWROUND) {
+		t.Fatal("next round dealt before everyone viewed the results")
+	}
+	g.ApplyMove(1, "R")
+	if events := g.Tick(now); !hasEvent(events, EVENT_NEWROUND) || g.Table.Status != TABLE_PLAYING {
+		t.Fatalf("next round wasn't dealt, events %v", events)
+	}
+}
+
+func TestIdlePlayers(t *testing.T) {
+	g := playingGame(3, "55", "66", "11")
+	now := time.Now()
+	g.Players[1].LastPolledTime = now.Add(-4 * time.Minute)
+
+	g.idlePlayerChange(now)
+	if g.Players[1].Human || g.Players[1].Name != "P2-AI" {
+		t.Errorf("player idle for 4 minutes = %q human %v, want an AI player P2-AI", g.Players[1].Name, g.Players[1].Human)
+	}
+	if !g.Players[0].Human || !g.Players[2].Human {
+		t.Error("players who are still polling were turned into AI players")
+	}
+
+	g.Players[2].LastPolledTime = now.Add(-6 * time.Minute)
+	g.idlePlayerRemoval(now)
+	if len(g.Players) != 2 || g.Table.CurPlayers != 2 || g.FindPlayer("P3") != -1 {
+		t.Errorf("human idle for 6 minutes wasn't removed, players %d", len(g.Players))
+	}
+	if g.FindPlayer("P2-AI") == -1 {
+		t.Error("the AI player that took over was removed")
+	}
+}
+
+func TestReclaimSeatAfterGoingIdle(t *testing.T) {
+	g := playingGame(3, "55", "66", "11")
+	g.Players[1].WhiteChips, g.Players[1].Score = 4, 4
+	now := time.Now()
+	g.Players[1].LastPolledTime = now.Add(-4 * time.Minute)
+	g.idlePlayerChange(now)
+
+	// Only the seat of someone who went idle can be reclaimed
+	if g.FindDisconnected("P1") != -1 || g.FindDisconnected("P2-AI") != -1 {
+		t.Error("found a disconnected seat that isn't one")
+	}
+	// Joining with the same name doesn't give anyone the seat (or the hand), only the player's session token does
+	if _, err := g.Join("P2", ""); err != ErrNameTaken || g.Players[1].Human {
+		t.Fatalf("joining with the idle player's name got %v, want ErrNameTaken", err)
+	}
+	events := g.Reclaim(g.FindDisconnected("P2"))
+	if !hasEvent(events, EVENT_REJOINED) {
+		t.Fatalf("reclaiming the seat got %v", events)
+	}
+	p2 := g.Players[1]
+	if p2.Name != "P2" || !p2.Human || p2.Disconnected || p2.Hand.HandSummary() != "66" || p2.Score != 4 {
+		t.Errorf("seat after rejoining = %+v", p2)
+	}
+	if _, err := g.Join("P2", ""); err != ErrNameTaken {
+		t.Errorf("joining again once back got %v, want ErrNameTaken", err)
+	}
+}
+
+func TestIdleTableCloses(t *testing.T) {
+	g := playingGame(3, "55", "66")
+	now := time.Now()
+	g.StartTime = now
+	g.Players[1].Human = false
+
+	if events := g.Tick(now); hasEvent(events, EVENT_RESET) {
+		t.Fatal("table closed with a human still playing")
+	}
+	// The seat is held for the last human while they can still come back for it
+	g.Players[0].LastPolledTime = now.Add(-4 * time.Minute)
+	if events := g.Tick(now); hasEvent(events, EVENT_RESET) || !g.Players[0].Disconnected {
+		t.Fatalf("table closed while the idle player could still come back, events %v", events)
+	}
+	g.Players[0].LastPolledTime = now.Add(-6 * time.Minute)
+	events := g.Tick(now)
+	if !hasEvent(events, EVENT_RESET) || g.Table.Status != TABLE_EMPTY {
+		t.Fatalf("table wasn't closed once the last human had gone for good, events %v", events)
+	}
+	if !strings.Contains(g.LastMovePlayed, "Waiting for players") {
+		t.Errorf("LastMovePlayed = %q, want the table waiting for players", g.LastMovePlayed)
+	}
+	if events := g.Tick(now); len(events) != 0 {
+		t.Errorf("an empty table did something, events %v", events)
+	}
+}
+
+func TestTurnClocks(t *testing.T) {
+	g := playingGame(3, "55", "66", "11")
+	g.Table.MoveTime, g.Table.TimeBank, g.Table.Extensions, g.Table.ExtensionTime = 10*time.Second, 20*time.Second, 1, 5*time.Second
+	g.setUpClocks()
+	now := time.Now()
+	g.StartTime = now
+
+	if left := g.TimeLeft(0, now); left != 30*time.Second {
+		t.Errorf("time left at the start of the turn = %v, want the move time and the bank", left)
+	}
+	if left := g.TimeLeft(1, now); left != 0 {
+		t.Errorf("time left when it isn't their turn = %v", left)
+	}
+	if events := g.Tick(now.Add(29 * time.Second)); len(events) != 0 {
+		t.Fatalf("clock ran out early, events %v", events)
+	}
+
+	// Out of time, so they get their grace extension and then they are folded
+	events := g.Tick(now.Add(30 * time.Second))
+	if !hasEvent(events, EVENT_EXTENDED) || g.Players[0].Extensions != 0 || g.TimeLeft(0, now.Add(30*time.Second)) != 5*time.Second {
+		t.Fatalf("no grace extension, events %v, player %+v", events, g.Players[0])
+	}
+	events = g.Tick(now.Add(35 * time.Second))
+	if !hasEvent(events, EVENT_FOLDED) || g.Players[0].TimeBank != 0 {
+		t.Fatalf("player wasn't folded once their extension ran out, events %v", events)
+	}
+
+	// Going 5 seconds over the move time comes out of the next player's bank
+	g.StartTime = time.Now().Add(-15 * time.Second)
+	if _, err := g.ApplyMove(1, "D"); err != nil {
+		t.Fatal(err)
+	}
+	if bank := g.Players[1].TimeBank; bank > 15*time.Second || bank < 14*time.Second {
+		t.Errorf("time bank after going 5 seconds over = %v, want 15s", bank)
+	}
+}
+
+func TestTurnClockStartsWhenTheHostStarts(t *testing.T) {
+	g := NewSeededGame(GameTable{Table: "test", MaxPlayers: 3, WaitTime: 5 * time.Minute, MoveTime: 30 * time.Second, TimeBank: time.Minute, Extensions: 1}, 1)
+	g.Join("A", "")
+	g.Join("B", "")
+	g.StartTime = time.Now().Add(-2 * time.Minute) // The last player joined 2 minutes ago
+
+	g.StartBy(0)
+	now := time.Now()
+	if left := g.TimeLeft(0, now); left < 89*time.Second || left > 90*time.Second {
+		t.Errorf("time left once the host started = %v, want the move time and the whole bank", left)
+	}
+	events := g.Tick(now.Add(time.Second))
+	if hasEvent(events, EVENT_EXTENDED) || hasEvent(events, EVENT_FOLDED) || g.Players[0].Extensions != 1 || g.Players[0].TimeBank != time.Minute {
+		t.Errorf("the first player's clock ran out before they could move, events %v, player %+v", events, g.Players[0])
+	}
+}
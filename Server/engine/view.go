@@ -0,0 +1,46 @@
+package engine
+
+// PublicPlayer is what anyone watching the table can see of a player, it never includes their hand
+type PublicPlayer struct {
+	Name       string `json:"n"`
+	Human      bool   `json:"h"`
+	Status     Status `json:"s"`
+	NumCards   int    `json:"nc"`
+	WhiteChips int    `json:"wt"`
+	BlackChips int    `json:"bt"`
+	Score      int    `json:"sc"`
+	RoundScore int    `json:"rs"`
+}
+
+// PublicGame is what anyone watching the table can see, without the hands, the deck order or the session tokens
+type PublicGame struct {
+	Table          GameTable      `json:"table"`
+	DrawDeck       int            `json:"dd"`
+	DiscardPile    int            `json:"dp"`
+	LastMovePlayed string         `json:"lmp"`
+	Players        []PublicPlayer `json:"pls"`
+}
+
+// PublicView returns the parts of the game that are safe to show to anyone
+func (g *Game) PublicView() PublicGame {
+	view := PublicGame{
+		Table:          g.Table,
+		DrawDeck:       g.NumCards,
+		DiscardPile:    g.Discard.Cardvalue,
+		LastMovePlayed: g.LastMovePlayed,
+		Players:        make([]PublicPlayer, len(g.Players)),
+	}
+	for i, player := range g.Players {
+		view.Players[i] = PublicPlayer{
+			Name:       player.Name,
+			Human:      player.Human,
+			Status:     player.Status,
+			NumCards:   player.NumCards,
+			WhiteChips: player.WhiteChips,
+			BlackChips: player.BlackChips,
+			Score:      player.Score,
+			RoundScore: player.RoundScore,
+		}
+	}
+	return view
+}
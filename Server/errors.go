@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorCode is the number sent with every error, so clients can tell errors apart without matching the message.
+// The numbers never change meaning (the 8 bit clients have them built in), new errors get new numbers.
+type ErrorCode int
+
+const (
+	ERR_NO_TABLE            ErrorCode = 1  // The table is missing or doesn't exist
+	ERR_NO_PLAYER_NAME      ErrorCode = 2  // Joining without a player name
+	ERR_NAME_TAKEN          ErrorCode = 3  // Someone at the table already has that name
+	ERR_GAME_IN_PROGRESS    ErrorCode = 4  // The table already has a game going
+	ERR_TABLE_FULL          ErrorCode = 5  // Every seat at the table is taken
+	ERR_NO_TOKEN            ErrorCode = 6  // The session token (tk) is missing
+	ERR_UNKNOWN_LEVEL       ErrorCode = 8  // The AI level isn't easy, medium, hard or expert (7 was player not found, before session tokens)
+	ERR_BAD_TOKEN           ErrorCode = 9  // The session token doesn't belong to anyone at the table
+	ERR_NO_HUMANS           ErrorCode = 10 // Starting a game before any human has joined
+	ERR_NO_MOVE             ErrorCode = 11 // A move was made without saying what it is (VM)
+	ERR_NOT_YOUR_TURN       ErrorCode = 12 // A move was made out of turn
+	ERR_INVALID_MOVE        ErrorCode = 13 // The move isn't one of the player's valid moves
+	ERR_ADMIN_OFF           ErrorCode = 14 // The admin endpoints are turned off
+	ERR_ADMIN_KEY           ErrorCode = 15 // The admin key is missing or wrong
+	ERR_TOO_MANY_SPECTATORS ErrorCode = 16 // Watching a table that already has as many spectators as it allows
+	ERR_BAD_TABLE           ErrorCode = 17 // Creating a private table with a set up that doesn't make sense
+	ERR_TOO_MANY_TABLES     ErrorCode = 18 // Creating a private table when there are already as many as the server allows
+	ERR_JOIN_CODE           ErrorCode = 19 // Joining or watching a private table without its join code
+	ERR_NOT_HOST            ErrorCode = 20 // Starting the game when you aren't the host
+)
+
+// errorCodes is the published list of errors, sent by /errors so clients can check what each code means
+var errorCodes = []struct {
+	Code    ErrorCode `json:"err"`
+	Status  int       `json:"status"`
+	Message string    `json:"msg"`
+}{
+	{ERR_NO_TABLE, http.StatusNotFound, "You need to specify a valid table"},
+	{ERR_NO_PLAYER_NAME, http.StatusBadRequest, "You need to supply a player name to join a table"},
+	{ERR_NAME_TAKEN, http.StatusConflict, "Someone is already at the table with that name"},
+	{ERR_GAME_IN_PROGRESS, http.StatusConflict, "The table has a game in progress"},
+	{ERR_TABLE_FULL, http.StatusConflict, "The table is full"},
+	{ERR_NO_TOKEN, http.StatusBadRequest, "You need to specify your session token"},
+	{ERR_UNKNOWN_LEVEL, http.StatusBadRequest, "Not an AI level, please use easy, medium, hard or expert"},
+	{ERR_BAD_TOKEN, http.StatusUnauthorized, "Your session token isn't valid at this table, please join the table again"},
+	{ERR_NO_HUMANS, http.StatusConflict, "The table has no human players, please join the table before starting a game"},
+	{ERR_NO_MOVE, http.StatusBadRequest, "You need to specify a move"},
+	{ERR_NOT_YOUR_TURN, http.StatusConflict, "It's not your turn to play"},
+	{ERR_INVALID_MOVE, http.StatusBadRequest, "That's not a valid move, please try again"},
+	{ERR_ADMIN_OFF, http.StatusForbidden, "Admin endpoints are turned off, set ADMIN_KEY on the server to use them"},
+	{ERR_ADMIN_KEY, http.StatusUnauthorized, "You need the admin key to do that"},
+	{ERR_TOO_MANY_SPECTATORS, http.StatusServiceUnavailable, "Too many people are watching the table, please try again later"},
+	{ERR_BAD_TABLE, http.StatusBadRequest, "The table set up isn't valid"},
+	{ERR_TOO_MANY_TABLES, http.StatusServiceUnavailable, "Too many private tables are open, please try again later"},
+	{ERR_JOIN_CODE, http.StatusForbidden, "You need the table's join code (code=) for a private table"},
+	{ERR_NOT_HOST, http.StatusForbidden, "Only the host can start the game, use /ready to say you are ready"},
+}
+
+// apiError is what every endpoint sends back when something goes wrong
+type apiError struct {
+	Code    ErrorCode `json:"err"`
+	Message string    `json:"msg"`
+}
+
+// String is the error the old way, "ERR(n) message", which is how it is sent with format=raw
+func (e apiError) String() string {
+	return fmt.Sprintf("ERR(%d) %s", e.Code, e.Message)
+}
+
+// errorInfo looks up the HTTP status and standard message for the error
+func errorInfo(code ErrorCode) (int, string) {
+	for _, info := range errorCodes {
+		if info.Code == code {
+			return info.Status, info.Message
+		}
+	}
+	return http.StatusInternalServerError, "Something went wrong"
+}
+
+// replyError sends the error with its HTTP status, using the standard message unless there is a more helpful one
+func replyError(c *gin.Context, code ErrorCode, message string) {
+	status, standard := errorInfo(code)
+	if message == "" {
+		message = standard
+	}
+	reply(c, status, apiError{Code: code, Message: message})
+	c.Abort() // Stop here if this was sent by middleware
+}
+
+// listErrors sends the published list of error codes
+func listErrors(c *gin.Context) {
+	c.JSON(http.StatusOK, errorCodes)
+}
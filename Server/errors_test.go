@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestErrorCodesAreUnique(t *testing.T) {
+	seen := map[ErrorCode]bool{}
+	for _, info := range errorCodes {
+		if seen[info.Code] {
+			t.Errorf("error code %d is listed twice", info.Code)
+		}
+		seen[info.Code] = true
+		if info.Status < 400 || info.Message == "" {
+			t.Errorf("error code %d has status %d and message %q", info.Code, info.Status, info.Message)
+		}
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setUpTables()
+	router := newRouter()
+
+	tests := []struct {
+		url    string
+		status int
+		code   ErrorCode
+	}{
+		{"/join?table=nowhere&player=Alice", http.StatusNotFound, ERR_NO_TABLE},
+		{"/join?table=ai1", http.StatusBadRequest, ERR_NO_PLAYER_NAME},
+		{"/join?table=ai1&player=Alice&level=godlike", http.StatusBadRequest, ERR_UNKNOWN_LEVEL},
+		{"/start?table=ai1", http.StatusBadRequest, ERR_NO_TOKEN},
+		{"/state?table=ai1", http.StatusBadRequest, ERR_NO_TOKEN},
+		{"/move?table=ai1&tk=ABCDEFGH&VM=F", http.StatusUnauthorized, ERR_BAD_TOKEN},
+		{"/devview", http.StatusForbidden, ERR_ADMIN_OFF},
+	}
+	for _, tt := range tests {
+		status, body := get(router, tt.url)
+		var got apiError
+		if err := json.Unmarshal(body, &got); err != nil || status != tt.status || got.Code != tt.code || got.Message == "" {
+			t.Errorf("%s = %d %s, want %d with error %d", tt.url, status, body, tt.status, tt.code)
+		}
+	}
+
+	// The raw format still sends the old ERR(n) line
+	if status, body := get(router, "/start?table=ai1&format=raw"); status != http.StatusBadRequest || string(body[:7]) != "ERR(6) " {
+		t.Errorf("raw error = %d %q", status, body)
+	}
+
+	// And the whole list is published
+	_, body := get(router, "/errors")
+	var list []apiError
+	if err := json.Unmarshal(body, &list); err != nil || len(list) != len(errorCodes) {
+		t.Errorf("/errors = %s", body)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"io"
+	"time"
+
+	"BunnyHop/server/engine"
+
+	"github.com/gin-gonic/gin"
+)
+
+// How many events a slow subscriber can fall behind by before we drop them
+const eventBufferSize = 64
+
+// How often a comment is sent down an idle event stream so proxies don't close it
+const eventKeepAlive = 15 * time.Second
+
+// publishEvents sends the events to everyone listening to the table (the caller must hold the table lock)
+func publishEvents(table *serverTable, events []engine.Event) {
+	for subscriber := range table.subscribers {
+	sending:
+		for _, event := range events {
+			select {
+			case subscriber <- event:
+			default:
+				// They aren't keeping up, so hang up on them rather than hold up the table
+				delete(table.subscribers, subscriber)
+				close(subscriber)
+				break sending
+			}
+		}
+	}
+}
+
+// streamEvents sends everything that happens at a table as Server-Sent Events, starting with what the table looks like now
+func streamEvents(c *gin.Context) {
+	table, ok := findTable(c)
+	if !ok {
+		replyError(c, ERR_NO_TABLE, "You need to specify a valid table to follow EG: /events?table=ai1")
+		return
+	}
+	events := make(chan engine.Event, eventBufferSize)
+	table.Lock()
+	if !checkJoinCode(c, table) {
+		table.Unlock()
+		return
+	}
+	table.subscribers[events] = struct{}{}
+	view := table.game.PublicView()
+	table.Unlock()
+	defer func() {
+		table.Lock()
+		delete(table.subscribers, events)
+		table.Unlock()
+	}()
+
+	c.Header("Cache-Control", "no-cache")
+	c.SSEvent("view", view)
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false // We dropped them for falling behind
+			}
+			c.SSEvent(string(event.Type), event)
+		case <-keepAlive.C:
+			io.WriteString(w, ": keep alive\n\n")
+		case <-c.Request.Context().Done():
+			return false
+		}
+		return true
+	})
+}
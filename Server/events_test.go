@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// nextEvent reads lines from the stream until it gets an event and returns its name and data
+func nextEvent(t *testing.T, lines *bufio.Scanner) (string, string) {
+	t.Helper()
+	var name string
+	for lines.Scan() {
+		line := lines.Text()
+		switch {
+		case strings.HasPrefix(line, "event:"):
+			name = line[len("event:"):]
+		case strings.HasPrefix(line, "data:"):
+			return name, line[len("data:"):]
+		}
+	}
+	t.Fatalf("event stream ended: %v", lines.Err())
+	return "", ""
+}
+
+func TestEventStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setUpTables()
+	router := newRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	if status, _ := get(router, "/events?table=nowhere"); status != http.StatusNotFound {
+		t.Errorf("/events for a missing table returned %d", status)
+	}
+
+	resp, err := http.Get(server.URL + "/events?table=ai3")
+	if err != nil {
+		t.Fatalf("couldn't follow the events: %v", err)
+	}
+	defer resp.Body.Close()
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		t.Errorf("Content-Type = %q", resp.Header.Get("Content-Type"))
+	}
+	lines := bufio.NewScanner(resp.Body)
+
+	// The first event is the table as it is now, after that we are subscribed
+	if name, _ := nextEvent(t, lines); name != "view" {
+		t.Fatalf("first event = %q, want view", name)
+	}
+
+	go func() {
+		token := join(t, router, "ai3", "Alice")
+		get(router, "/start?table=ai3&tk="+token)
+	}()
+	want := []string{"joined", "started"}
+	done := time.AfterFunc(5*time.Second, func() { resp.Body.Close() })
+	defer done.Stop()
+	for _, wantName := range want {
+		name, data := nextEvent(t, lines)
+		if name != wantName {
+			t.Fatalf("got event %q %s, want %q", name, data, wantName)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"time"
+
+	"BunnyHop/server/engine"
+)
+
+// How often each table's game loop checks its timers and AI players
+const tableTickInterval = 500 * time.Millisecond
+
+// runTableLoop drives a table's timers and AI players in the background,
+// so games keep moving at the same pace no matter how often (or if) the clients poll
+func runTableLoop(table *serverTable) {
+	ticker := time.NewTicker(tableTickInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		table.Lock()
+		updateTable(table)
+		closing := table.retiring && table.game.Table.Status == engine.TABLE_EMPTY
+		turn, thinking := table.game.NextAITurn(time.Now())
+		table.Unlock()
+		if thinking {
+			playAITurn(table, turn)
+		}
+		if closing && removeTable(table) {
+			return // The table was taken out of the tables file and its game is over
+		}
+	}
+}
+
+// updateTable runs one tick of the game loop for the table (the caller must hold the table lock)
+func updateTable(table *serverTable) {
+	now := time.Now()
+	handleEvents(table, table.game.Tick(now))
+	dropIdleSpectators(table, now)
+	expirePrivateTable(table, now)
+}
+
+// playAITurn lets the expert AI think about its move without holding the table lock, so the players' requests
+// aren't held up while it does. The move is only made if nothing has happened at the table in the meantime
+func playAITurn(table *serverTable, turn engine.AITurn) {
+	move := turn.Choose()
+	table.Lock()
+	defer table.Unlock()
+	if !table.removed {
+		handleEvents(table, table.game.FinishAITurn(turn, move))
+	}
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"BunnyHop/server/engine"
+
+	"github.com/gin-gonic/gin"
+)
+
+// setUpTables gives every table a new game that always deals the same cards
+func setUpTables() {
+	tablesLock.Lock()
+	defer tablesLock.Unlock()
+	tables = nil
+	for i, config := range defaultTables {
+		tables = append(tables, newServerTable(engine.NewSeededGame(config, int64(i+1))))
+	}
+}
+
+// get makes a request to the router and returns the status and body
+func get(router *gin.Engine, url string) (int, []byte) {
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+	return recorder.Code, recorder.Body.Bytes()
+}
+
+// join sits a player at a table and returns their session token
+func join(t *testing.T, router *gin.Engine, table string, player string) string {
+	status, body := get(router, "/join?table="+table+"&player="+player)
+	var joined struct {
+		ShortToken string `json:"tk"`
+		Token      string `json:"token"`
+	}
+	if err := json.Unmarshal(body, &joined); status != http.StatusOK || err != nil {
+		t.Errorf("%s couldn't join %s: %d %s", player, table, status, body)
+	}
+	return joined.Token
+}
+
+// TestConcurrentPlay has players polling and moving on every table at once while the game loops tick,
+// run it with -race to check the tables are properly locked
+func TestConcurrentPlay(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setUpTables()
+	adminKey = "test"
+	defer func() { adminKey = "" }()
+	router := newRouter()
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for _, table := range tables {
+		for p := 1; p <= 2; p++ {
+			wg.Add(1)
+			go func(table string, player string) {
+				defer wg.Done()
+				_, body := get(router, "/join?table="+table+"&player="+player)
+				var joined struct {
+					Token string `json:"token"`
+				}
+				if json.Unmarshal(body, &joined) != nil {
+					return // The other player started the game before this one could sit down
+				}
+				get(router, "/start?table="+table+"&tk="+joined.Token)
+				token := joined.Token
+				for i := 0; i < 100; i++ {
+					status, body := get(router, "/state?table="+table+"&tk="+token)
+					if status != http.StatusOK {
+						continue
+					}
+					var state struct {
+						Players []struct {
+							Name      string `json:"n"`
+							ValidMove string `json:"pvm"`
+						} `json:"pls"`
+					}
+					if err := json.Unmarshal(body, &state); err != nil {
+						t.Errorf("bad state from %s: %v", table, err)
+						return
+					}
+					for _, p := range state.Players {
+						if p.Name == player && p.ValidMove != "" {
+							get(router, "/move?table="+table+"&tk="+token+"&VM="+p.ValidMove[:1])
+						}
+					}
+				}
+			}(table.id, fmt.Sprintf("P%d", p))
+		}
+	}
+
+	// Everyone else just watches
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			get(router, "/tables")
+			get(router, "/view")
+			getAsAdmin(router, "/devview", "test")
+		}
+	}()
+
+	// The game loops keep ticking while all that is going on
+	var loops sync.WaitGroup
+	loops.Add(1)
+	go func() {
+		defer loops.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			for _, table := range allTables() {
+				table.Lock()
+				updateTable(table)
+				table.Unlock()
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(done)
+	loops.Wait()
+
+	status, body := get(router, "/tables")
+	if status != http.StatusOK {
+		t.Fatalf("/tables returned %d", status)
+	}
+	var list []engine.GameTable
+	if err := json.Unmarshal(body, &list); err != nil || len(list) != len(tables) {
+		t.Fatalf("/tables returned %s", body)
+	}
+}
+
+// TestLongPollState checks /state?since= waits for the next version and answers straight away for an old one
+func TestLongPollState(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setUpTables()
+	router := newRouter()
+	token := join(t, router, "ai2", "Alice")
+
+	var state struct {
+		Version int `json:"v"`
+	}
+	_, body := get(router, "/state?table=ai2&tk="+token)
+	if err := json.Unmarshal(body, &state); err != nil || state.Version == 0 {
+		t.Fatalf("/state has no version: %s", body)
+	}
+	first := state.Version
+
+	// An old version is already out of date so comes straight back
+	_, body = get(router, fmt.Sprintf("/state?table=ai2&tk=%s&since=%d", token, first-1))
+	json.Unmarshal(body, &state)
+	if state.Version != first {
+		t.Fatalf("since an old version got version %d, want %d", state.Version, first)
+	}
+
+	// The current version waits until the game starts
+	waited := make(chan []byte)
+	go func() {
+		_, body := get(router, fmt.Sprintf("/state?table=ai2&tk=%s&since=%d", token, first))
+		waited <- body
+	}()
+	select {
+	case body = <-waited:
+		t.Fatalf("didn't wait for a new state: %s", body)
+	case <-time.After(100 * time.Millisecond):
+	}
+	get(router, "/start?table=ai2&tk="+token)
+	select {
+	case body = <-waited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("still waiting after the game started")
+	}
+	json.Unmarshal(body, &state)
+	if state.Version <= first {
+		t.Errorf("version after the start = %d, want more than %d", state.Version, first)
+	}
+}
+
+func TestLeave(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setUpTables()
+	router := newRouter()
+	join(t, router, "garden", "Alice")
+	bob := join(t, router, "garden", "Bob")
+
+	if status, body := get(router, "/leave?table=garden&tk="+bob); status != http.StatusOK || string(body) != `"Bob left the table"` {
+		t.Fatalf("/leave = %d %s", status, body)
+	}
+	if tables[0].game.Table.CurPlayers != 1 || tables[0].game.HasPlayer("Bob") {
+		t.Errorf("Bob is still at the table: %+v", tables[0].game.Players)
+	}
+	if status, _ := get(router, "/state?table=garden&tk="+bob); status != http.StatusUnauthorized {
+		t.Errorf("Bob's token still works after leaving, got %d", status)
+	}
+	if status, _ := get(router, "/leave?table=garden&tk="+bob); status != http.StatusUnauthorized {
+		t.Errorf("leaving twice got %d", status)
+	}
+}
+
+func TestOpponentsHandsAreHidden(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setUpTables()
+	router := newRouter()
+	token := join(t, router, "ai2", "Alice")
+	get(router, "/start?table=ai2&tk="+token)
+
+	var state struct {
+		Players []playerState `json:"pls"`
+	}
+	_, body := get(router, "/state?table=ai2&tk="+token)
+	if err := json.Unmarshal(body, &state); err != nil || len(state.Players) != 3 {
+		t.Fatalf("/state = %s", body)
+	}
+	for _, player := range state.Players {
+		switch {
+		case player.Name == "Alice" && (player.HandSummary == "" || len(player.HandSummary) != player.NumCards):
+			t.Errorf("Alice can't see their own hand: %+v", player)
+		case player.Name != "Alice" && (player.HandSummary != "" || player.ValidMove != ""):
+			t.Errorf("Alice can see %s's hand: %+v", player.Name, player)
+		case player.NumCards == 0:
+			t.Errorf("%s's card count is missing: %+v", player.Name, player)
+		}
+	}
+
+	// Everyone's hand is turned over once the round is over
+	tables[2].Lock()
+	tables[2].game.EndRound()
+	tables[2].Unlock()
+	_, body = get(router, "/state?table=ai2&tk="+token)
+	json.Unmarshal(body, &state)
+	for i, player := range state.Players {
+		if player.HandSummary != tables[2].game.HandSummary(i) {
+			t.Errorf("%s's hand at the round over = %q, want %q", player.Name, player.HandSummary, tables[2].game.HandSummary(i))
+		}
+	}
+}
+
+func TestHostStartsTheGame(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setUpTables()
+	router := newRouter()
+	alice := join(t, router, "garden", "Alice")
+	bob := join(t, router, "garden", "Bob")
+
+	var state struct {
+		Host string `json:"hn"`
+	}
+	_, body := get(router, "/state?table=garden&tk="+bob)
+	if json.Unmarshal(body, &state); state.Host != "Alice" {
+		t.Errorf("host = %q, want Alice: %s", state.Host, body)
+	}
+	if status, body := get(router, "/start?table=garden&tk="+bob); status != http.StatusForbidden {
+		t.Errorf("Bob starting the game = %d %s", status, body)
+	}
+
+	// Everyone saying they are ready starts the game too
+	if status, body := get(router, "/ready?table=garden&tk="+bob); status != http.StatusOK || string(body) != `"Bob is ready"` {
+		t.Errorf("/ready = %d %s", status, body)
+	}
+	if tables[0].game.Table.Status != engine.TABLE_WAITING {
+		t.Fatalf("the game started before Alice was ready")
+	}
+	get(router, "/ready?table=garden&tk="+alice)
+	if tables[0].game.Table.Status != engine.TABLE_PLAYING {
+		t.Errorf("the game didn't start when everyone was ready, status = %d", tables[0].game.Table.Status)
+	}
+}
+
+func TestStateShowsTurnClocks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setUpTables()
+	router := newRouter()
+	id, code := create(t, router, "players=2&bots=1&moveTime=10s&timeBank=1m&extensions=2")
+	token := join(t, router, id+"&code="+code, "Alice")
+	get(router, "/start?table="+id+"&tk="+token)
+
+	var state struct {
+		Players []playerState `json:"pls"`
+	}
+	_, body := get(router, "/state?table="+id+"&tk="+token)
+	if err := json.Unmarshal(body, &state); err != nil || len(state.Players) != 2 {
+		t.Fatalf("/state = %s", body)
+	}
+	alice, bot := state.Players[0], state.Players[1]
+	if alice.TimeLeft < 69 || alice.TimeLeft > 70 || alice.TimeBank != 60 || alice.Extensions != 2 {
+		t.Errorf("Alice's clock = %d left, %d in the bank, %d extensions", alice.TimeLeft, alice.TimeBank, alice.Extensions)
+	}
+	if bot.TimeLeft != 0 || bot.TimeBank != 60 {
+		t.Errorf("the AI player's clock = %d left, %d in the bank", bot.TimeLeft, bot.TimeBank)
+	}
+}
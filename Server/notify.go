@@ -0,0 +1,20 @@
+package main
+
+import "time"
+
+// How long /state?since= waits for a new state before sending back the one the client already has
+const longPollTimeout = 25 * time.Second
+
+// Each table has a channel (serverTable.changed) that is closed and replaced with a new one whenever the table changes,
+// so anything waiting for a change (like a WebSocket) can wait on it without polling
+
+// notifyTable wakes up everything waiting for the table to change (the caller must hold the table lock)
+func notifyTable(table *serverTable) {
+	close(table.changed)
+	table.changed = make(chan struct{})
+}
+
+// tableChange returns a channel that is closed the next time the table changes (the caller must hold the table lock)
+func tableChange(table *serverTable) <-chan struct{} {
+	return table.changed
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"BunnyHop/server/engine"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The most private tables that can be open at once
+const maxPrivateTables = 50
+
+// A private table nobody sits down at within this long is closed
+const privateTableTimeout = 10 * time.Minute
+
+// How many letters are in a private table's join code
+const joinCodeLength = 6
+
+// Private table ids start with "p_", the tables file can't use an underscore so they never clash with its tables
+const privateTablePrefix = "p_"
+
+// createTable opens a private table set up the way the player asks, it isn't listed in /tables
+// and joining it (or watching it) needs the join code that is sent back
+func createTable(c *gin.Context) {
+	config := tableConfig{
+		Name:          c.Query("name"),
+		MaxPlayers:    maxTablePlayers,
+		BotLevel:      c.Query("level"),
+		Variant:       c.Query("variant"),
+		HumansOnly:    c.Query("humansOnly") == "1" || c.Query("humansOnly") == "true",
+		WaitTime:      c.Query("wait"), // EG: 2m
+		MoveTime:      c.Query("moveTime"),
+		TimeBank:      c.Query("timeBank"),
+		ExtensionTime: c.Query("extensionTime"),
+	}
+	if players := c.Query("players"); players != "" {
+		config.MaxPlayers, _ = strconv.Atoi(players) // Anything that isn't a number is caught by the check below
+	}
+	config.MaxBots = config.MaxPlayers - 1 // Fill the empty seats with AI players unless asked not to
+	if bots := c.Query("bots"); bots != "" {
+		config.MaxBots, _ = strconv.Atoi(bots)
+	}
+	if extensions := c.Query("extensions"); extensions != "" {
+		var err error
+		if config.Extensions, err = strconv.Atoi(extensions); err != nil {
+			config.Extensions = -1 // Caught by the check below
+		}
+	}
+	if config.Name == "" {
+		config.Name = "Private table"
+	}
+	config.ID = "x" // Checked with a stand in id, the table gets its real one when it is opened
+	setup, err := config.gameTable()
+	if err != nil {
+		replyError(c, ERR_BAD_TABLE, "Sorry: "+err.Error())
+		return
+	}
+
+	table, ok := openPrivateTable(setup)
+	if !ok {
+		replyError(c, ERR_TOO_MANY_TABLES, "")
+		return
+	}
+	c.JSON(http.StatusOK, struct {
+		Table    string `json:"t"`
+		JoinCode string `json:"code"`
+		Message  string `json:"msg"`
+	}{table.id, table.joinCode, "Private table " + setup.Name + " is open, join it with /join?table=" + table.id + "&code=" + table.joinCode})
+}
+
+// checkJoinCode makes sure the request has the join code for a private table (the code= query parameter, any case).
+// Returns false after sending an error back if it doesn't (the caller must hold the table lock)
+func checkJoinCode(c *gin.Context, table *serverTable) bool {
+	if !table.private || strings.ToUpper(c.Query("code")) == table.joinCode {
+		return true
+	}
+	replyError(c, ERR_JOIN_CODE, "")
+	return false
+}
+
+// expirePrivateTable closes a private table nobody has sat down at in time (the caller must hold the table lock)
+func expirePrivateTable(table *serverTable, now time.Time) {
+	if table.private && !table.retiring && table.game.Table.Status == engine.TABLE_EMPTY && now.Sub(table.opened) > privateTableTimeout {
+		fmt.Println("Nobody sat down at private table", table.id, "closing it")
+		table.retiring = true
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// create opens a private table and returns its id and join code
+func create(t *testing.T, router *gin.Engine, query string) (string, string) {
+	status, body := get(router, "/create?"+query)
+	var created struct {
+		Table    string `json:"t"`
+		JoinCode string `json:"code"`
+	}
+	if err := json.Unmarshal(body, &created); status != http.StatusOK || err != nil || created.Table == "" || created.JoinCode == "" {
+		t.Fatalf("/create?%s = %d %s", query, status, body)
+	}
+	return created.Table, created.JoinCode
+}
+
+func TestPrivateTables(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setUpTables()
+	router := newRouter()
+	id, code := create(t, router, "name=Friday+Night&players=4&bots=2&level=hard")
+
+	// It isn't listed anywhere public
+	if _, body := get(router, "/tables"); strings.Contains(string(body), id) {
+		t.Errorf("the private table is in /tables: %s", body)
+	}
+	if _, body := get(router, "/view"); strings.Contains(string(body), "Friday Night") {
+		t.Errorf("the private table is in /view: %s", body)
+	}
+
+	// Joining and watching need the code
+	if status, _ := get(router, "/join?table="+id+"&player=Alice"); status != http.StatusForbidden {
+		t.Errorf("joining without the code got %d", status)
+	}
+	if status, _ := get(router, "/join?table="+id+"&player=Alice&code=WRONG"); status != http.StatusForbidden {
+		t.Errorf("joining with the wrong code got %d", status)
+	}
+	if status, _ := get(router, "/watch?table="+id); status != http.StatusForbidden {
+		t.Errorf("watching without the code got %d", status)
+	}
+	token := join(t, router, id+"&code="+strings.ToLower(code), "Alice")
+
+	table, _ := tableByID(id)
+	table.Lock()
+	setup := table.game.Table
+	table.Unlock()
+	if setup.Name != "Friday Night" || setup.MaxPlayers != 4 || setup.MaxBots != 2 || setup.BotLevel != "hard" || setup.CurPlayers != 1 {
+		t.Errorf("private table set up = %+v", setup)
+	}
+
+	// Reloading the tables file leaves it alone
+	reloadTables(defaultTables)
+	if !slices.Contains(tableIDs(), id) || table.retiring {
+		t.Errorf("reloading the tables file closed the private table")
+	}
+
+	// Once everyone has left it closes (its game loop may get there first)
+	get(router, "/leave?table="+id+"&tk="+token)
+	removeTable(table)
+	if slices.Contains(tableIDs(), id) {
+		t.Fatalf("the private table is still open after everyone left: %v", tableIDs())
+	}
+	if status, _ := get(router, "/join?table="+id+"&player=Alice&code="+code); status != http.StatusNotFound {
+		t.Errorf("joining the closed table got %d", status)
+	}
+}
+
+func TestCreateTableChecksTheSetUp(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setUpTables()
+	router := newRouter()
+	for _, query := range []string{"players=9", "players=lots", "players=3&bots=3", "level=impossible", "variant=backwards", "moveTime=soon", "moveTime=500ms", "extensions=some"} {
+		if status, body := get(router, "/create?"+query); status != http.StatusBadRequest {
+			t.Errorf("/create?%s = %d %s", query, status, body)
+		}
+	}
+}
+
+func TestUnusedPrivateTablesClose(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setUpTables()
+	router := newRouter()
+	id, _ := create(t, router, "")
+	table, _ := tableByID(id)
+
+	table.Lock()
+	expirePrivateTable(table, time.Now())
+	soon := table.retiring
+	expirePrivateTable(table, time.Now().Add(privateTableTimeout+time.Second))
+	later := table.retiring
+	table.Unlock()
+	if soon || !later {
+		t.Errorf("closing straight away = %v, closing after the timeout = %v", soon, later)
+	}
+}
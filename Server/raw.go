@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// With format=raw, /tables, /state and /move answer with fixed width lines of plain text instead of JSON,
+// so the 8 bit clients can INPUT each line straight into a string and pick the fields out by position.
+// Every line ends with a LF (FujiNet's translation mode turns it into an ATASCII EOL) and any character
+// that isn't printable ASCII is sent as a "?".
+//
+// /tables sends the number of tables, then a line per table:
+//
+//	NN                                     number of tables (2 digits)
+//	TTTTTTTTNNNNNNNNNNNNNNNNNNNNPMS        table id (8), name (20), players (1), max players (1), status (1)
+//
+// /state sends the table on the first two lines, then a line per player:
+//
+//	DDPSCV...                              draw deck (2), discard (1), table status (1), number of players (1), version (the rest of the line)
+//	LLLL...                                last move played (40)
+//	NNNNNNNNNNSCCWWBBMMMMRHTTTKKKEHHHH...  name (10), status (1), cards (2), white chips (2), black chips (2), valid moves (4),
+//	                                       ready (1), host (1), seconds left to move (3), seconds left in the time bank (3),
+//	                                       grace extensions left (1), hand (the rest of the line)
+//
+// /move sends just the message on one line, and errors are sent as one line starting with ERR(n)
+const (
+	rawTableIDWidth    = 8
+	rawTableNameWidth  = 20
+	rawMessageWidth    = 40
+	rawPlayerNameWidth = 10
+	rawValidMoveWidth  = 4
+)
+
+// wantsRaw checks if the request asked for the fixed width text format
+func wantsRaw(c *gin.Context) bool {
+	return c.Query("format") == "raw"
+}
+
+// reply sends the response as JSON, or as fixed width text if the request asked for format=raw
+func reply(c *gin.Context, code int, obj any) {
+	if !wantsRaw(c) {
+		c.JSON(code, obj)
+		return
+	}
+	var text string
+	switch v := obj.(type) {
+	case string:
+		text = rawLine(v, 0)
+	case apiError:
+		text = rawLine(v.String(), 0)
+	case []tableListing:
+		text = rawTables(v)
+	case gameStateResponse:
+		text = rawGameState(v)
+	default:
+		c.JSON(code, obj) // Nothing else has a text layout yet
+		return
+	}
+	c.Data(code, "text/plain; charset=us-ascii", []byte(text))
+}
+
+// rawTables lays out the table list for format=raw
+func rawTables(tableList []tableListing) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "%02d\n", len(tableList))
+	for _, table := range tableList {
+		out.WriteString(rawField(table.Table, rawTableIDWidth))
+		out.WriteString(rawField(table.Name, rawTableNameWidth))
+		fmt.Fprintf(&out, "%s%s%s\n", rawDigit(table.CurPlayers), rawDigit(table.MaxPlayers), rawDigit(table.Status))
+	}
+	return out.String()
+}
+
+// rawGameState lays out a player's game state for format=raw
+func rawGameState(state gameStateResponse) string {
+	var out strings.Builder
+	fmt.Fprintf(&out, "%02d%s%s%s%d\n", state.DrawDeck, rawDigit(state.DiscardPile), rawDigit(state.TablesStatus), rawDigit(len(state.Players)), state.Version)
+	out.WriteString(rawLine(state.LastMovePlayed, rawMessageWidth))
+	for _, player := range state.Players {
+		out.WriteString(rawField(player.Name, rawPlayerNameWidth))
+		fmt.Fprintf(&out, "%s%02d%02d%02d", rawDigit(int(player.Status)), player.NumCards, player.WhiteChips, player.BlackChips)
+		out.WriteString(rawField(player.ValidMove, rawValidMoveWidth))
+		fmt.Fprintf(&out, "%s%s%s%s%s", rawFlag(player.Ready), rawFlag(player.Name == state.Host), rawSeconds(player.TimeLeft), rawSeconds(player.TimeBank), rawDigit(player.Extensions))
+		out.WriteString(rawLine(player.HandSummary, 0))
+	}
+	return out.String()
+}
+
+// rawField makes the text exactly width characters long, cutting it short or padding it with spaces
+func rawField(text string, width int) string {
+	text = rawText(text)
+	if len(text) > width {
+		return text[:width]
+	}
+	return text + strings.Repeat(" ", width-len(text))
+}
+
+// rawLine makes the text a line on its own, padded to width if width isn't 0
+func rawLine(text string, width int) string {
+	if width > 0 {
+		return rawField(text, width) + "\n"
+	}
+	return rawText(text) + "\n"
+}
+
+// rawDigit is a single digit field, anything that doesn't fit is sent as 9
+func rawDigit(n int) string {
+	if n < 0 || n > 9 {
+		n = 9
+	}
+	return string(rune('0' + n))
+}
+
+// rawFlag is a yes or no field, 1 or 0
+func rawFlag(on bool) string {
+	if on {
+		return "1"
+	}
+	return "0"
+}
+
+// rawSeconds is a 3 digit number of seconds, anything longer is sent as 999
+func rawSeconds(seconds int) string {
+	return fmt.Sprintf("%03d", min(max(seconds, 0), 999))
+}
+
+// rawText replaces anything that isn't printable ASCII (including line breaks) with a "?"
+func rawText(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r < ' ' || r > '~' {
+			return '?'
+		}
+		return r
+	}, text)
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRawField(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  string
+	}{
+		{"ai1", 8, "ai1     "},
+		{"Cave of Caerbannog!!!", 20, "Cave of Caerbannog!!"},
+		{"Zoë\n", 5, "Zo?? "},
+	}
+	for _, tt := range tests {
+		if got := rawField(tt.text, tt.width); got != tt.want {
+			t.Errorf("rawField(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestRawFormat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setUpTables()
+	router := newRouter()
+
+	_, body := get(router, "/tables?format=raw")
+	lines := strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")
+	if len(lines) != len(tables)+1 || lines[0] != "07" {
+		t.Fatalf("/tables?format=raw = %q", body)
+	}
+	if lines[2] != "ai1     AI Room - 1 bots    060" {
+		t.Errorf("ai1 line = %q", lines[2])
+	}
+
+	token := join(t, router, "ai4", "Alice")
+	get(router, "/start?table=ai4&tk="+token)
+	_, body = get(router, "/state?format=raw&table=ai4&tk="+token)
+	lines = strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")
+	// 25 cards left after dealing, a 1 turned up, playing, 5 players and the 2nd version (join then start)
+	if len(lines) != 7 || lines[0] != "251352" {
+		t.Fatalf("/state?format=raw = %q", body)
+	}
+	if len(lines[1]) != rawMessageWidth {
+		t.Errorf("last move line is %d long, want %d", len(lines[1]), rawMessageWidth)
+	}
+	// Alice has 6 cards, is the host and it's Alice's turn, with 59 or 60 seconds of the default move time left and no time bank
+	if alice := lines[2]; !strings.HasPrefix(alice, "Alice     1060000") || len(alice) != 36 {
+		t.Errorf("Alice's line = %q", alice)
+	} else if clocks := alice[21:30]; clocks != "010600000" && clocks != "010590000" {
+		t.Errorf("Alice's ready, host and clock fields = %q", clocks)
+	}
+	// The AI players aren't ready or the host, and it isn't their turn
+	if bot := lines[3]; len(bot) != 30 || bot[21:30] != "000000000" {
+		t.Errorf("AI player's line = %q", bot)
+	}
+
+	// Ready flags show up before the game starts
+	bob := join(t, router, "ai1", "Bob")
+	carol := join(t, router, "ai1", "Carol")
+	get(router, "/ready?table=ai1&tk="+bob)
+	_, body = get(router, "/state?format=raw&table=ai1&tk="+carol)
+	lines = strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")
+	if len(lines) != 4 || lines[2][21:23] != "11" || lines[3][21:23] != "00" {
+		t.Errorf("/state?format=raw before the game = %q", body)
+	}
+
+	// Errors are still a single line starting with ERR(n)
+	status, body := get(router, "/state?format=raw&table=ai4&tk=nope")
+	if status != http.StatusUnauthorized || !strings.HasPrefix(string(body), "ERR(9)") || strings.Count(string(body), "\n") != 1 {
+		t.Errorf("bad token gave %d %q", status, body)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Short tokens are made of capital letters only, so they are easy to type, safe in a URL,
+// and the Atari client can't mistake one for an "ERR(n)" error number
+const shortTokenLetters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+const shortTokenLength = 8
+
+// newSessionTokens makes a new session token and its short form for a player joining or watching the table (the caller must hold the table lock)
+func newSessionTokens(table *serverTable) (string, string) {
+	for {
+		token, short := randomToken(), randomShortToken()
+		// Make sure nobody else at the table (or watching it) already has it (very unlikely)
+		if table.game.FindToken(token) == -1 && table.game.FindToken(short) == -1 && findSpectator(table, token) == nil && findSpectator(table, short) == nil {
+			return token, short
+		}
+	}
+}
+
+// randomToken returns 16 random bytes as hex
+func randomToken() string {
+	buf := make([]byte, 16)
+	rand.Read(buf) // Never returns an error
+	return hex.EncodeToString(buf)
+}
+
+// randomShortToken returns a short token of random capital letters
+func randomShortToken() string {
+	return randomLetters(shortTokenLength)
+}
+
+// randomLetters returns random capital letters, skipping the bytes that would make some letters more likely than others
+func randomLetters(length int) string {
+	short := make([]byte, 0, length)
+	buf := make([]byte, 1)
+	for len(short) < length {
+		rand.Read(buf)
+		if int(buf[0]) < 256-256%len(shortTokenLetters) {
+			short = append(short, shortTokenLetters[int(buf[0])%len(shortTokenLetters)])
+		}
+	}
+	return string(short)
+}
+
+// findSessionPlayer finds the player making the request from their session token (the tk query parameter).
+// If the request names a player as well, the token has to belong to them.
+// A player who went idle and was taken over by an AI player gets their seat back as soon as they use their token again.
+// Returns the player's index, or -1 after sending an error back if the token isn't valid (the caller must hold the table lock)
+func findSessionPlayer(c *gin.Context, table *serverTable) int {
+	playerIndex := table.game.FindToken(c.Query("tk"))
+	if playerIndex != -1 && table.game.Players[playerIndex].Disconnected {
+		handleEvents(table, table.game.Reclaim(playerIndex))
+	}
+	if playerIndex == -1 || (c.Query("player") != "" && c.Query("player") != table.game.Players[playerIndex].Name) {
+		replyError(c, ERR_BAD_TOKEN, "")
+		return -1
+	}
+	return playerIndex
+}
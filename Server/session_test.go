@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"BunnyHop/server/engine"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSessionTokens(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setUpTables()
+	router := newRouter()
+
+	// The short token comes first in the join response, and only has capital letters in it
+	status, body := get(router, "/join?table=ai1&player=BOB")
+	if status != http.StatusOK || !strings.HasPrefix(string(body), `{"tk":"`) {
+		t.Fatalf("join = %d %s, want the short token first", status, body)
+	}
+	var bob struct {
+		ShortToken string `json:"tk"`
+		Token      string `json:"token"`
+	}
+	json.Unmarshal(body, &bob)
+	if len(bob.ShortToken) != shortTokenLength || strings.Trim(bob.ShortToken, shortTokenLetters) != "" {
+		t.Errorf("short token %q should be %d capital letters", bob.ShortToken, shortTokenLength)
+	}
+	if len(bob.Token) != 32 {
+		t.Errorf("token %q should be 32 characters", bob.Token)
+	}
+	alice := join(t, router, "ai1", "ALICE")
+	get(router, "/start?table=ai1&tk="+bob.Token)
+
+	tests := []struct {
+		name   string
+		url    string
+		status int
+		prefix string
+	}{
+		{"state with the token", "/state?table=ai1&tk=" + bob.Token, http.StatusOK, `{"dd"`},
+		{"state with the short token", "/state?table=ai1&tk=" + bob.ShortToken, http.StatusOK, `{"dd"`},
+		{"state with the token and name", "/state?table=ai1&player=BOB&tk=" + bob.ShortToken, http.StatusOK, `{"dd"`},
+		{"state with no token", "/state?table=ai1&player=BOB", http.StatusBadRequest, `{"err":6,`},
+		{"state with a made up token", "/state?table=ai1&player=BOB&tk=ABCDEFGH", http.StatusUnauthorized, `{"err":9,`},
+		{"state with someone else's token", "/state?table=ai1&player=BOB&tk=" + alice, http.StatusUnauthorized, `{"err":9,`},
+		{"state at another table", "/state?table=ai2&tk=" + bob.Token, http.StatusUnauthorized, `{"err":9,`},
+		{"move with no token", "/move?table=ai1&player=BOB&VM=F", http.StatusBadRequest, `{"err":6,`},
+		{"move with someone else's token", "/move?table=ai1&player=BOB&VM=F&tk=" + alice, http.StatusUnauthorized, `{"err":9,`},
+		{"move with the token", "/move?table=ai1&VM=F&tk=" + bob.ShortToken, http.StatusOK, `"BOB folded"`},
+	}
+	for _, tt := range tests {
+		status, body := get(router, tt.url)
+		if status != tt.status || !strings.HasPrefix(string(body), tt.prefix) {
+			t.Errorf("%s: got %d %s, want %d %s...", tt.name, status, body, tt.status, tt.prefix)
+		}
+	}
+}
+
+func TestReclaimSeatWithToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setUpTables()
+	router := newRouter()
+	token := join(t, router, "ai5", "ALICE") // On their own against the AI players
+	get(router, "/start?table=ai5&tk="+token)
+
+	// ALICE's Wi-Fi drops for long enough that an AI player takes over
+	tables[5].Lock()
+	tables[5].game.Players[0].LastPolledTime = time.Now().Add(-4 * time.Minute)
+	tables[5].game.Tick(time.Now())
+	tables[5].Unlock()
+	if tables[5].game.Players[0].Human || tables[5].game.Table.Status != engine.TABLE_PLAYING {
+		t.Fatalf("ALICE didn't go idle (or the table closed), table status %d", tables[5].game.Table.Status)
+	}
+
+	// Someone else joining as ALICE doesn't get the seat or the hand
+	if status, _ := get(router, "/join?table=ai5&player=ALICE"); status != http.StatusConflict || tables[5].game.Players[0].Human {
+		t.Errorf("joining with the idle player's name got %d", status)
+	}
+
+	// As soon as ALICE polls again the seat is given back
+	status, body := get(router, "/state?table=ai5&player=ALICE&tk="+token)
+	if status != http.StatusOK || !tables[5].game.Players[0].Human || tables[5].game.Players[0].Name != "ALICE" {
+		t.Errorf("polling after going idle got %d %s, seat %+v", status, body, tables[5].game.Players[0])
+	}
+}
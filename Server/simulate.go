@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"BunnyHop/server/engine"
+)
+
+// simSeat keeps the results for one seat (strategy) across all the simulated games
+type simSeat struct {
+	name   string
+	level  string
+	wins   float64 // Shared wins count as a fraction
+	rounds int
+	score  int
+	folds  int
+}
+
+// runSimulation plays games between AI strategies without the web server and reports how each one did.
+// Usage: server simulate -games 1000 -players easy,medium,hard,expert -think 2ms
+func runSimulation(args []string) {
+	flags := flag.NewFlagSet("simulate", flag.ExitOnError)
+	games := flags.Int("games", 1000, "number of games to play")
+	players := flags.String("players", "easy,medium,hard,expert", "comma separated AI level for each seat (2 to 6 players)")
+	think := flags.Duration("think", 2*time.Millisecond, "how long the expert AI can think about each move")
+	seed := flags.Int64("seed", 0, "seed for the deals and seating, to play the same games again (random if not set)")
+	flags.Parse(args)
+
+	levels := strings.Split(*players, ",")
+	if len(levels) < 2 || len(levels) > 6 {
+		fmt.Println("Need between 2 and 6 players to simulate a game")
+		os.Exit(1)
+	}
+	seats := make([]*simSeat, len(levels))
+	for i, level := range levels {
+		level = strings.TrimSpace(level)
+		if engine.AIStrategies[level] == nil {
+			fmt.Println("Unknown AI level:", level, "(use easy, medium, hard or expert)")
+			os.Exit(1)
+		}
+		seats[i] = &simSeat{name: fmt.Sprintf("%s-%d", level, i+1), level: level}
+	}
+
+	started := time.Now()
+	totalRounds := 0
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(*seed))
+	for game := 0; game < *games; game++ {
+		totalRounds += simulateGame(seats, *think, rng)
+	}
+
+	fmt.Printf("Simulated %d games with %d players in %s\n", *games, len(seats), time.Since(started).Round(time.Millisecond))
+	fmt.Printf("Average rounds per game: %.2f\n\n", float64(totalRounds)/float64(*games))
+	fmt.Printf("%-12s %9s %16s %10s\n", "Player", "Win rate", "Avg score/round", "Fold rate")
+	for _, seat := range seats {
+		fmt.Printf("%-12s %8.1f%% %16.2f %9.1f%%\n", seat.name, seat.winRate(*games), seat.averageScore(), seat.foldRate())
+	}
+}
+
+// winRate is the percentage of the games the seat won
+func (seat *simSeat) winRate(games int) float64 {
+	return 100 * seat.wins / float64(games)
+}
+
+// averageScore is how many points the seat scored in an average round
+func (seat *simSeat) averageScore() float64 {
+	return float64(seat.score) / float64(seat.rounds)
+}
+
+// foldRate is the percentage of the rounds the seat folded in
+func (seat *simSeat) foldRate() float64 {
+	return 100 * float64(seat.folds) / float64(seat.rounds)
+}
+
+// simulateGame plays one full game between the seats and returns the number of rounds it took,
+// rng picks the seating and the game's seed
+func simulateGame(seats []*simSeat, think time.Duration, rng *rand.Rand) int {
+	game := engine.NewSeededGame(engine.GameTable{Table: "sim", Name: "Simulation", MaxPlayers: len(seats), BotThinkTime: think}, rng.Int63())
+	game.SetLogger(engine.NoLogger) // The game logs every move, keep the output to just the report
+	bySeat := make(map[string]*simSeat)
+
+	// Seat the players in a random order so no strategy always gets to go first
+	for _, seatIndex := range rng.Perm(len(seats)) {
+		seat := seats[seatIndex]
+		bySeat[seat.name] = seat
+		game.AddBot(seat.name, seat.level)
+	}
+	game.Start()
+
+	rounds := 0
+	for {
+		// Play the round out, one move at a time
+		for moves := 0; moves < 1000 && !game.RoundEnded(); moves++ {
+			for _, event := range game.PlayAITurn() {
+				if event.Type == engine.EVENT_FOLDED {
+					bySeat[event.Player].folds++
+				}
+			}
+		}
+		game.EndRound()
+		rounds++
+		for _, player := range game.Players {
+			bySeat[player.Name].rounds++
+			bySeat[player.Name].score += player.RoundScore
+		}
+		if game.Gameover {
+			break
+		}
+		game.NextRound()
+	}
+
+	// The lowest score wins, anyone tied for the lowest shares the win
+	lowest := game.Players[0].Score
+	for _, player := range game.Players {
+		if player.Score < lowest {
+			lowest = player.Score
+		}
+	}
+	winners := []string{}
+	for _, player := range game.Players {
+		if player.Score == lowest {
+			winners = append(winners, player.Name)
+		}
+	}
+	for _, name := range winners {
+		bySeat[name].wins += 1 / float64(len(winners))
+	}
+	return rounds
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestEachAILevelBeatsTheOneBelowIt(t *testing.T) {
+	// The expert AI takes too long to play hundreds of games in a test, see TestMonteCarloOnlyMakesValidMoves for it
+	seats := []*simSeat{{name: "easy", level: "easy"}, {name: "medium", level: "medium"}, {name: "hard", level: "hard"}}
+	rng := rand.New(rand.NewSource(1))
+	for game := 0; game < 400; game++ {
+		simulateGame(seats, time.Millisecond, rng)
+	}
+	easy, medium, hard := seats[0], seats[1], seats[2]
+	if easy.wins >= medium.wins || medium.wins >= hard.wins {
+		t.Errorf("wins: easy %.1f, medium %.1f, hard %.1f, want each level winning more than the one below it", easy.wins, medium.wins, hard.wins)
+	}
+}
+
+func TestSimulateGame(t *testing.T) {
+	play := func(seed int64) ([]*simSeat, int) {
+		seats := []*simSeat{{name: "easy-1", level: "easy"}, {name: "medium-2", level: "medium"}, {name: "hard-3", level: "hard"}}
+		rng := rand.New(rand.NewSource(seed))
+		totalRounds := 0
+		for game := 0; game < 5; game++ {
+			rounds := simulateGame(seats, time.Millisecond, rng)
+			if rounds < 1 {
+				t.Fatalf("game %d took %d rounds", game, rounds)
+			}
+			totalRounds += rounds
+		}
+		return seats, totalRounds
+	}
+	seats, totalRounds := play(7)
+
+	// Every seat plays every round, and the wins (shared wins included) make up every game
+	winRates := 0.0
+	for _, seat := range seats {
+		if seat.rounds != totalRounds {
+			t.Errorf("%s played %d rounds, want %d", seat.name, seat.rounds, totalRounds)
+		}
+		if seat.folds > seat.rounds || seat.foldRate() < 0 || seat.foldRate() > 100 {
+			t.Errorf("%s folded in %d of %d rounds", seat.name, seat.folds, seat.rounds)
+		}
+		winRates += seat.winRate(5)
+	}
+	if winRates < 99.999 || winRates > 100.001 {
+		t.Errorf("the win rates add up to %.3f%%, want 100%%", winRates)
+	}
+
+	// The same seed plays the same games
+	again, againRounds := play(7)
+	for i := range seats {
+		if againRounds != totalRounds || *again[i] != *seats[i] {
+			t.Errorf("the same seed played differently: %+v then %+v", *seats[i], *again[i])
+		}
+	}
+}
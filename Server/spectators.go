@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// The most people that can watch one table at a time
+const maxSpectators = 50
+
+// Spectators who haven't asked for the state (or had a WebSocket open) for this long are dropped
+const spectatorTimeout = 5 * time.Minute
+
+// spectatorView is used in place of a player index for someone watching the table, they see the state without anyone's cards
+const spectatorView = -1
+
+// spectator is someone watching a table without a seat, they don't count towards the players at the table
+type spectator struct {
+	name       string    // Who is watching, only used in the log and messages
+	token      string    // Session token sent back with /state, /ws and /leave
+	shortToken string    // Short form of the token for the 8 bit clients
+	lastSeen   time.Time // When they last asked for the state
+}
+
+// findSpectator finds the spectator with the session token, or nil if nobody is watching with it (the caller must hold the table lock)
+func findSpectator(table *serverTable, token string) *spectator {
+	if token == "" {
+		return nil
+	}
+	for _, watcher := range table.spectators {
+		if watcher.token == token || watcher.shortToken == token {
+			return watcher
+		}
+	}
+	return nil
+}
+
+// lookUpViewer finds who the session token belongs to: a player's index, or spectatorView for a spectator (which counts as them being seen).
+// Returns false if nobody at the table has the token (the caller must hold the table lock)
+func lookUpViewer(table *serverTable, token string) (int, bool) {
+	if watcher := findSpectator(table, token); watcher != nil {
+		watcher.lastSeen = time.Now()
+		return spectatorView, true
+	}
+	playerIndex := table.game.FindToken(token)
+	return playerIndex, playerIndex != -1
+}
+
+// findSessionViewer is findSessionPlayer for the endpoints spectators can use as well,
+// returns spectatorView for a spectator, or false after sending an error back if the token isn't valid (the caller must hold the table lock)
+func findSessionViewer(c *gin.Context, table *serverTable) (int, bool) {
+	if watcher := findSpectator(table, c.Query("tk")); watcher != nil {
+		watcher.lastSeen = time.Now()
+		return spectatorView, true
+	}
+	playerIndex := findSessionPlayer(c, table)
+	return playerIndex, playerIndex != -1
+}
+
+// dropIdleSpectators stops counting the spectators who have stopped watching (the caller must hold the table lock)
+func dropIdleSpectators(table *serverTable, now time.Time) {
+	for token, watcher := range table.spectators {
+		if now.Sub(watcher.lastSeen) > spectatorTimeout {
+			fmt.Println(watcher.name, "stopped watching table", table.id)
+			delete(table.spectators, token)
+		}
+	}
+}
+
+// watchTable lets someone follow a table without taking a seat, they get a session token to use with /state, /ws and /leave
+func watchTable(c *gin.Context) {
+	table, ok := findTable(c)
+	if !ok {
+		replyError(c, ERR_NO_TABLE, "You need to specify a valid table to watch EG: /watch?table=ai1")
+		return
+	}
+	table.Lock()
+	defer table.Unlock()
+	if table.removed {
+		replyError(c, ERR_NO_TABLE, "Sorry: table "+table.id+" has closed, please try a different table")
+		return
+	}
+	if !checkJoinCode(c, table) {
+		return
+	}
+
+	name := c.Query("player") // Optional, so we know who is watching
+	if name == "" {
+		name = "Spectator"
+	}
+	dropIdleSpectators(table, time.Now()) // Make room if anyone has wandered off
+	if len(table.spectators) >= maxSpectators {
+		replyError(c, ERR_TOO_MANY_SPECTATORS, "")
+		return
+	}
+
+	watcher := &spectator{name: name, lastSeen: time.Now()}
+	watcher.token, watcher.shortToken = newSessionTokens(table)
+	table.spectators[watcher.token] = watcher
+	fmt.Println(name, "is watching table", table.id)
+	c.JSON(http.StatusOK, struct {
+		ShortToken string `json:"tk"`
+		Token      string `json:"token"`
+		Message    string `json:"msg"`
+	}{watcher.shortToken, watcher.token, name + " is watching table " + table.id})
+}
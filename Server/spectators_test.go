@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// watch starts watching a table and returns the spectator's session token
+func watch(t *testing.T, router *gin.Engine, table string, name string) string {
+	status, body := get(router, "/watch?table="+table+"&player="+name)
+	var watching struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(body, &watching); status != http.StatusOK || err != nil || watching.Token == "" {
+		t.Fatalf("%s couldn't watch %s: %d %s", name, table, status, body)
+	}
+	return watching.Token
+}
+
+// listedTable returns the table from /tables
+func listedTable(t *testing.T, router *gin.Engine, id string) tableListing {
+	_, body := get(router, "/tables")
+	var list []tableListing
+	if err := json.Unmarshal(body, &list); err != nil {
+		t.Fatalf("/tables returned %s", body)
+	}
+	for _, table := range list {
+		if table.Table == id {
+			return table
+		}
+	}
+	t.Fatalf("%s isn't in /tables: %s", id, body)
+	return tableListing{}
+}
+
+func TestSpectators(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setUpTables()
+	router := newRouter()
+	alice := join(t, router, "ai2", "Alice")
+	projector := watch(t, router, "ai2", "Projector")
+
+	listed := listedTable(t, router, "ai2")
+	if listed.CurPlayers != 1 || listed.Spectators != 1 {
+		t.Errorf("/tables shows %d players and %d spectators, want 1 and 1", listed.CurPlayers, listed.Spectators)
+	}
+
+	// Watching doesn't take a seat, so the game starts with Alice and the 2 AI players
+	get(router, "/start?table=ai2&tk="+alice)
+	var state struct {
+		TablesStatus int           `json:"ts"`
+		Players      []playerState `json:"pls"`
+	}
+	status, body := get(router, "/state?table=ai2&tk="+projector)
+	if err := json.Unmarshal(body, &state); status != http.StatusOK || err != nil || len(state.Players) != 3 {
+		t.Fatalf("spectator /state = %d %s", status, body)
+	}
+	for _, player := range state.Players {
+		if player.HandSummary != "" || player.ValidMove != "" {
+			t.Errorf("the spectator can see %s's hand: %+v", player.Name, player)
+		}
+	}
+
+	// Spectators can't play
+	if status, _ := get(router, "/move?table=ai2&VM=F&tk="+projector); status != http.StatusUnauthorized {
+		t.Errorf("a spectator's move got %d", status)
+	}
+
+	if status, body := get(router, "/leave?table=ai2&tk="+projector); status != http.StatusOK || string(body) != `"Projector stopped watching the table"` {
+		t.Errorf("/leave = %d %s", status, body)
+	}
+	if listed := listedTable(t, router, "ai2"); listed.Spectators != 0 {
+		t.Errorf("after leaving /tables shows %d spectators", listed.Spectators)
+	}
+	if status, _ := get(router, "/state?table=ai2&tk="+projector); status != http.StatusUnauthorized {
+		t.Errorf("the spectator's token still works after leaving, got %d", status)
+	}
+}
+
+func TestIdleSpectatorsAreDropped(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setUpTables()
+	router := newRouter()
+	watching := watch(t, router, "garden", "Watching")
+	wandered := watch(t, router, "garden", "Wandered")
+
+	tables[0].Lock()
+	findSpectator(tables[0], wandered).lastSeen = time.Now().Add(-spectatorTimeout - time.Second)
+	dropIdleSpectators(tables[0], time.Now())
+	tables[0].Unlock()
+
+	if status, _ := get(router, "/state?table=garden&tk="+watching); status != http.StatusOK {
+		t.Errorf("the spectator who is still watching got %d", status)
+	}
+	if status, _ := get(router, "/state?table=garden&tk="+wandered); status != http.StatusUnauthorized {
+		t.Errorf("the idle spectator got %d", status)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"BunnyHop/server/engine"
+)
+
+// StateStore keeps a copy of each table's game so games in progress survive the server being restarted
+// (Cloud Run scales to zero when nobody is playing, see deploy.sh)
+type StateStore interface {
+	Save(table string, data []byte) error
+	Load(table string) ([]byte, error) // Returns nil if nothing has been saved for the table
+	Delete(table string) error         // Forgets the table, for tables the server no longer runs
+	Tables() ([]string, error)         // The tables that have something saved
+}
+
+// fileStore saves each table as a JSON file in a directory
+type fileStore struct {
+	dir string
+}
+
+func (store fileStore) path(table string) string {
+	return filepath.Join(store.dir, table+".json")
+}
+
+// Save writes to a temporary file first and then renames it, so a crash part way through never leaves half a game behind
+func (store fileStore) Save(table string, data []byte) error {
+	if err := os.MkdirAll(store.dir, 0o755); err != nil {
+		return err
+	}
+	tmp := store.path(table) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, store.path(table))
+}
+
+func (store fileStore) Load(table string) ([]byte, error) {
+	data, err := os.ReadFile(store.path(table))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (store fileStore) Delete(table string) error {
+	err := os.Remove(store.path(table))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil // Nothing was saved for it
+	}
+	return err
+}
+
+func (store fileStore) Tables() ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(store.dir, "*.json"))
+	var tables []string
+	for _, path := range paths {
+		tables = append(tables, strings.TrimSuffix(filepath.Base(path), ".json"))
+	}
+	return tables, err
+}
+
+// noStore doesn't keep anything, every restart starts with empty tables
+type noStore struct{}
+
+func (noStore) Save(table string, data []byte) error { return nil }
+func (noStore) Load(table string) ([]byte, error)    { return nil, nil }
+func (noStore) Delete(table string) error            { return nil }
+func (noStore) Tables() ([]string, error)            { return nil, nil }
+
+// newStateStore picks the state store from the STATE_STORE environment variable,
+// "file" (the default) saves to the STATE_DIR directory and "none" turns saving off
+func newStateStore() (StateStore, error) {
+	switch kind := os.Getenv("STATE_STORE"); kind {
+	case "", "file":
+		dir := os.Getenv("STATE_DIR")
+		if dir == "" {
+			dir = "state"
+		}
+		log.Printf("Saving tables to %s", dir)
+		return fileStore{dir: dir}, nil
+	case "none":
+		return noStore{}, nil
+	default:
+		return nil, fmt.Errorf("unknown STATE_STORE %q, use file or none", kind)
+	}
+}
+
+// tableSaver writes a table's snapshots to the state store in the background, so a slow store (like a
+// Cloud Storage mount) doesn't hold up the table. Only the latest snapshot is kept while a write is in progress.
+type tableSaver struct {
+	id      string
+	store   StateStore // Where the table is saved, picked when the table is set up
+	lock    sync.Mutex // Guards the fields below
+	pending []byte     // The snapshot waiting to be written, nil if there isn't one
+	remove  bool       // The table's saved game is to be deleted rather than written
+	running bool       // A background writer is working through the pending change
+	writing sync.Mutex // Held while writing to the store, so the changes are made in order
+}
+
+// save queues the snapshot to be written, in place of any snapshot still waiting
+func (saver *tableSaver) save(data []byte) {
+	saver.lock.Lock()
+	defer saver.lock.Unlock()
+	saver.pending, saver.remove = data, false
+	saver.start()
+}
+
+// delete queues the table's saved game to be deleted, any snapshot still waiting is dropped
+func (saver *tableSaver) delete() {
+	saver.lock.Lock()
+	defer saver.lock.Unlock()
+	saver.pending, saver.remove = nil, true
+	saver.start()
+}
+
+// start runs a background writer if one isn't already running (the caller must hold saver.lock)
+func (saver *tableSaver) start() {
+	if !saver.running {
+		saver.running = true
+		go func() {
+			for saver.writeNext() {
+			}
+		}()
+	}
+}
+
+// flush writes any pending change straight away, returning once it is in the store
+func (saver *tableSaver) flush() {
+	for saver.writeNext() {
+	}
+}
+
+// writeNext makes the pending change to the state store, returns false if there wasn't one
+func (saver *tableSaver) writeNext() bool {
+	saver.writing.Lock()
+	defer saver.writing.Unlock()
+	saver.lock.Lock()
+	data, remove := saver.pending, saver.remove
+	saver.pending, saver.remove = nil, false
+	if data == nil && !remove {
+		saver.running = false // Nothing left to do, the next change starts a new writer
+	}
+	saver.lock.Unlock()
+
+	switch {
+	case remove:
+		if err := saver.store.Delete(saver.id); err != nil {
+			fmt.Println("Couldn't delete the saved game for table", saver.id, ":", err)
+		}
+	case data != nil:
+		if err := saver.store.Save(saver.id, data); err != nil {
+			fmt.Println("Couldn't save table", saver.id, ":", err)
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// saveTable snapshots the table's game, it is written to the state store in the background (the caller must hold the table lock).
+// Private tables aren't saved, their join codes aren't kept so nobody could get back to them after a restart
+func saveTable(table *serverTable) {
+	if table.private {
+		return
+	}
+	data, err := table.game.Snapshot()
+	if err != nil {
+		fmt.Println("Couldn't save table", table.id, ":", err)
+		return
+	}
+	table.saver.save(data)
+}
+
+// deleteTable removes the table's saved game from the state store, in the background like saveTable (the caller must hold the table lock)
+func deleteTable(table *serverTable) {
+	table.saver.delete()
+}
+
+// loadTable restores the table's game from the state store, or returns false if there isn't a saved game for it
+func loadTable(table *serverTable) bool {
+	data, err := stateStore.Load(table.id)
+	if err != nil {
+		fmt.Println("Couldn't load table", table.id, ":", err)
+		return false
+	}
+	if data == nil {
+		return false
+	}
+	game, err := engine.RestoreGame(table.game.Table, data) // The new game on the table still has the table set up as it is in the tables file
+	if err != nil {
+		fmt.Println("Couldn't restore table", table.id, ":", err)
+		return false
+	}
+	table.game = game
+	fmt.Println("Restored table", table.id, "with", len(game.Players), "players")
+	return true
+}
+
+// deletePrivateTables removes any private tables left in the state store by an older server, they are never restored
+func deletePrivateTables() {
+	saved, err := stateStore.Tables()
+	if err != nil {
+		fmt.Println("Couldn't list the saved tables:", err)
+	}
+	for _, id := range saved {
+		if !strings.HasPrefix(id, privateTablePrefix) {
+			continue
+		}
+		if err := stateStore.Delete(id); err != nil {
+			fmt.Println("Couldn't delete the saved game for private table", id, ":", err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	store := fileStore{dir: t.TempDir() + "/state"}
+	data, err := store.Load("garden")
+	if data != nil || err != nil {
+		t.Fatalf("Load before anything was saved = %q, %v, want nothing", data, err)
+	}
+	if err := store.Save("garden", []byte(`{"a":1}`)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := store.Save("garden", []byte(`{"a":2}`)); err != nil {
+		t.Fatalf("Save over the top: %v", err)
+	}
+	data, err = store.Load("garden")
+	if err != nil || !bytes.Equal(data, []byte(`{"a":2}`)) {
+		t.Fatalf("Load = %q, %v, want the last save", data, err)
+	}
+}
+
+func TestTablesSurviveARestart(t *testing.T) {
+	stateStore = fileStore{dir: t.TempDir()}
+	defer func() { stateStore = noStore{} }()
+	setUpTables()
+
+	tables[2].Lock()
+	events, _ := tables[2].game.Join("P1", "")
+	handleEvents(tables[2], events)
+	events, _ = tables[2].game.Start()
+	handleEvents(tables[2], events)
+	hand := tables[2].game.HandSummary(0)
+	tables[2].Unlock()
+	tables[2].saver.flush()
+
+	// Start again from nothing and load the saved tables
+	setUpTables()
+	for i := range tables {
+		if loaded := loadTable(tables[i]); loaded != (i == 2) {
+			t.Errorf("loadTable(%d) = %v", i, loaded)
+		}
+	}
+	if tables[2].game.FindPlayer("P1") != 0 || tables[2].game.Table.Status != 3 || tables[2].game.HandSummary(0) != hand {
+		t.Errorf("table wasn't restored, got %+v", tables[2].game)
+	}
+}
+
+func TestPrivateTablesAreNotKept(t *testing.T) {
+	store := fileStore{dir: t.TempDir()}
+	stateStore = store
+	defer func() { stateStore = noStore{} }()
+	setUpTables()
+
+	// Nothing is saved for a private table
+	table, _ := openPrivateTable(defaultTables[0])
+	table.Lock()
+	events, _ := table.game.Join("P1", "")
+	handleEvents(table, events)
+	table.Unlock()
+	if data, _ := store.Load(table.id); data != nil {
+		t.Errorf("the private table was saved: %s", data)
+	}
+
+	// Private tables left behind by an older server are deleted when it starts
+	store.Save("p_left", []byte(`{}`))
+	store.Save("garden", []byte(`{}`))
+	deletePrivateTables()
+	if saved, err := store.Tables(); err != nil || len(saved) != 1 || saved[0] != "garden" {
+		t.Errorf("saved tables = %v, %v, want just garden", saved, err)
+	}
+}
+
+// slowStore is a state store whose saves wait until they are let through
+type slowStore struct {
+	noStore
+	through chan struct{}
+	saved   chan []byte
+}
+
+func (store slowStore) Save(table string, data []byte) error {
+	<-store.through
+	store.saved <- data
+	return nil
+}
+
+func TestSavingDoesntHoldUpTheTable(t *testing.T) {
+	store := slowStore{through: make(chan struct{}), saved: make(chan []byte, 10)}
+	stateStore = store
+	defer func() { stateStore = noStore{} }()
+	setUpTables()
+	table := tables[0]
+
+	// Every change is saved without waiting for the store, even while it is stuck on the first save
+	for _, name := range []string{"P1", "P2", "P3"} {
+		table.Lock()
+		events, _ := table.game.Join(name, "")
+		handleEvents(table, events)
+		table.Unlock()
+	}
+
+	// Once the store gets going the saves still waiting are skipped, only the latest one is written
+	close(store.through)
+	table.saver.flush()
+	close(store.saved)
+	var saves [][]byte
+	for data := range store.saved {
+		saves = append(saves, data)
+	}
+	if len(saves) == 0 || len(saves) > 2 || !bytes.Contains(saves[len(saves)-1], []byte("P3")) {
+		t.Errorf("saved %q, want at most the save already under way and then the latest", saves)
+	}
+}
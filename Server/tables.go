@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"BunnyHop/server/engine"
+
+	"github.com/gin-gonic/gin"
+)
+
+// serverTable is a table the server is running, its game and everything the server keeps alongside it
+type serverTable struct {
+	sync.Mutex                                 // Guards everything below, hold it while using the game
+	id          string                         // The table's id (EG: garden), never changes so it can be read without the lock
+	game        *engine.Game                   // The game being played at the table
+	changed     chan struct{}                  // Closed (and replaced) whenever the table changes, see notify.go
+	subscribers map[chan engine.Event]struct{} // Everyone following the table's events, see events.go
+	spectators  map[string]*spectator          // Everyone watching the table without a seat, by session token, see spectators.go
+	retiring    bool                           // Taken out of the tables file (or a private table everyone has left), the table is closed once its game is over
+	removed     bool                           // No longer run by the server, nobody can join it
+	private     bool                           // Opened with /create, it isn't listed and joining it needs the join code, see private.go
+	joinCode    string                         // The code needed to join a private table
+	opened      time.Time                      // When the private table was opened
+	saver       *tableSaver                    // Writes the table's game to the state store in the background, see store.go
+}
+
+// The tables the server is running, in the order they are listed
+var tablesLock sync.RWMutex // Guards the list, not the tables in it
+var tables []*serverTable
+
+// GAME_SEED makes every table shuffle the same way each time the server starts (for reproducing a game)
+var gameSeed int64
+var seeded bool
+
+// newServerTable sets up a table to run the game
+func newServerTable(game *engine.Game) *serverTable {
+	return &serverTable{
+		id:          game.Table.Table,
+		game:        game,
+		changed:     make(chan struct{}),
+		subscribers: map[chan engine.Event]struct{}{},
+		spectators:  map[string]*spectator{},
+		saver:       &tableSaver{id: game.Table.Table, store: stateStore},
+	}
+}
+
+// startTable sets up a new table, carries on with its saved game if there is one, and starts its game loop.
+// position is where it is in the list, used to give each table its own seed.
+func startTable(config engine.GameTable, position int) *serverTable {
+	var game *engine.Game
+	if seeded {
+		game = engine.NewSeededGame(config, gameSeed+int64(position)) // Each table gets its own seed so they don't all deal the same cards
+	} else {
+		game = engine.NewGame(config) // Set up the table with a new deck and shuffle it
+	}
+	table := newServerTable(game)
+	loadTable(table)       // Carry on with the game that was saved before the server stopped (if there was one)
+	go runTableLoop(table) // Start the game loop that drives the timers and AI players for the table
+	return table
+}
+
+// findTable finds the table named by the table query parameter
+// Returns the table and a boolean indicating a vaild table was found
+func findTable(c *gin.Context) (*serverTable, bool) {
+	return tableByID(c.Query("table"))
+}
+
+// tableByID finds the table with the id
+func tableByID(id string) (*serverTable, bool) {
+	tablesLock.RLock()
+	defer tablesLock.RUnlock()
+	return tableByIDLocked(id)
+}
+
+// tableByIDLocked is tableByID for when the caller already holds tablesLock
+func tableByIDLocked(id string) (*serverTable, bool) {
+	for _, table := range tables {
+		if table.id == id {
+			return table, true
+		}
+	}
+	return nil, false
+}
+
+// allTables returns the tables the server is running, in the order they are listed
+func allTables() []*serverTable {
+	tablesLock.RLock()
+	defer tablesLock.RUnlock()
+	return slices.Clone(tables)
+}
+
+// openPrivateTable opens a new private table with its own id and join code and starts its game loop.
+// Returns false if there are already too many private tables open
+func openPrivateTable(config engine.GameTable) (*serverTable, bool) {
+	tablesLock.Lock()
+	defer tablesLock.Unlock()
+
+	private := 0
+	for _, table := range tables {
+		if strings.HasPrefix(table.id, privateTablePrefix) {
+			private++
+		}
+	}
+	if private >= maxPrivateTables {
+		return nil, false
+	}
+	for {
+		config.Table = privateTablePrefix + strings.ToLower(randomLetters(joinCodeLength))
+		if _, taken := tableByIDLocked(config.Table); !taken {
+			break
+		}
+	}
+	table := startTable(config, len(tables))
+	table.Lock() // The game loop is already running
+	table.private = true
+	table.joinCode = randomLetters(joinCodeLength)
+	table.opened = time.Now()
+	table.Unlock()
+	tables = append(tables, table)
+	fmt.Println("Opened private table", table.id, "-", config.Name)
+	return table, true
+}
+
+// reloadTables brings the tables into line with the tables file without stopping any games:
+// new tables are opened, the ones still there pick up their new set up once they are empty,
+// and the ones taken out of the file are closed once their game is over
+func reloadTables(configs []engine.GameTable) {
+	tablesLock.Lock()
+	defer tablesLock.Unlock()
+
+	existing := map[string]*serverTable{}
+	for _, table := range tables {
+		existing[table.id] = table
+	}
+	list := make([]*serverTable, 0, len(configs))
+	for _, config := range configs {
+		table := existing[config.Table]
+		if table == nil {
+			fmt.Println("Opening table", config.Table)
+			list = append(list, startTable(config, len(list)))
+			continue
+		}
+		delete(existing, config.Table)
+		table.Lock()
+		table.game.Reconfigure(config)
+		table.retiring = false
+		table.Unlock()
+		list = append(list, table)
+	}
+	for _, table := range tables {
+		if existing[table.id] == nil {
+			continue
+		}
+		table.Lock()
+		if !table.private { // Private tables aren't in the tables file, they close once everyone has left
+			fmt.Println("Closing table", table.id, "once its game is over")
+			table.retiring = true
+		}
+		table.Unlock()
+		list = append(list, table) // Still listed until it closes, so the players can carry on
+	}
+	tables = list
+}
+
+// removeTable stops running a retiring table once it is empty, anyone still following it is told the table has gone.
+// Returns false if the table has to stay (it was put back in the tables file or someone sat down in the meantime).
+func removeTable(table *serverTable) bool {
+	tablesLock.Lock()
+	defer tablesLock.Unlock()
+	table.Lock()
+	defer table.Unlock()
+	if !table.retiring || table.game.Table.Status != engine.TABLE_EMPTY {
+		return false
+	}
+	if i := slices.Index(tables, table); i != -1 {
+		tables = slices.Delete(tables, i, i+1)
+	}
+	table.removed = true
+	for subscriber := range table.subscribers {
+		delete(table.subscribers, subscriber)
+		close(subscriber)
+	}
+	notifyTable(table)
+	deleteTable(table) // Its game is over, so there is nothing to carry on with if it opens again
+	fmt.Println("Closed table", table.id)
+	return true
+}
+
+// reloadTablesOnHangup reloads the tables file every time the server gets a SIGHUP,
+// if the file has a mistake in it the tables are left as they are
+func reloadTablesOnHangup(path string) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	for range hangup {
+		configs, err := loadTablesConfig(path)
+		if err != nil {
+			fmt.Println("Couldn't reload the tables from", path, ":", err)
+			continue
+		}
+		reloadTables(configs)
+		fmt.Println("Reloaded", len(configs), "tables from", path)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"BunnyHop/server/engine"
+
+	"github.com/gin-gonic/gin"
+)
+
+// tableIDs lists the ids of the tables the server is running
+func tableIDs() []string {
+	ids := []string{}
+	for _, table := range allTables() {
+		ids = append(ids, table.id)
+	}
+	return ids
+}
+
+func TestReloadTables(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setUpTables()
+	router := newRouter()
+	token := join(t, router, "ai5", "Alice") // ai5 has a game waiting to start
+
+	// Rename the garden, add a river and take out everything else
+	garden := defaultTables[0]
+	garden.Name = "The Secret Garden"
+	river := engine.GameTable{Table: "river", Name: "The River", MaxPlayers: 4, MaxBots: 3, BotLevel: engine.AI_HARD, HumansOnly: true}
+	reloadTables([]engine.GameTable{garden, river})
+
+	// Tables being closed stay listed after the new list until they are empty
+	if ids := tableIDs(); !slices.Equal(ids, []string{"garden", "river", "ai1", "ai2", "ai3", "ai4", "ai5", "cave"}) {
+		t.Fatalf("tables after reloading = %v", ids)
+	}
+	if status, body := get(router, "/view?table=garden"); status != http.StatusOK || !strings.Contains(string(body), "The Secret Garden") {
+		t.Errorf("the empty garden wasn't renamed: %s", body)
+	}
+	if status, _ := get(router, "/join?table=river&player=Bob"); status != http.StatusOK {
+		t.Errorf("couldn't join the new table, got %d", status)
+	}
+	if status, _ := get(router, "/join?table=ai5&player=Carol"); status != http.StatusNotFound {
+		t.Errorf("joined a table that is closing, got %d", status)
+	}
+
+	// Empty tables close straight away, ai5 waits until Alice has gone
+	for _, table := range allTables() {
+		if removed := removeTable(table); removed != (table.id != "garden" && table.id != "river" && table.id != "ai5") {
+			t.Errorf("removeTable(%s) = %v", table.id, removed)
+		}
+	}
+	if status, _ := get(router, "/state?table=ai5&tk="+token); status != http.StatusOK {
+		t.Errorf("Alice's game on ai5 was dropped, got %d", status)
+	}
+	get(router, "/leave?table=ai5&tk="+token)
+	ai5, _ := tableByID("ai5")
+	if !removeTable(ai5) || !slices.Equal(tableIDs(), []string{"garden", "river"}) {
+		t.Errorf("ai5 didn't close once empty, tables %v", tableIDs())
+	}
+}
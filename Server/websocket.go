@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// How often a WebSocket checks the state even when nothing has happened at the table,
+// this also counts as the player polling so they don't go idle while they are connected
+const wsRefreshInterval = 30 * time.Second
+
+// How long sending the state to a WebSocket can take before we give up on the connection
+const wsWriteTimeout = 10 * time.Second
+
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true }, // All origins allowed, the same as the CORS setup
+}
+
+// watchGameState upgrades to a WebSocket that sends the player the same game state as /state every time it changes
+func watchGameState(c *gin.Context) {
+	table, ok := findTable(c)
+	token := c.Query("tk")
+	if !ok {
+		replyError(c, ERR_NO_TABLE, "")
+		return
+	}
+	if token == "" {
+		replyError(c, ERR_NO_TOKEN, "")
+		return
+	}
+	table.Lock()
+	_, ok = findSessionViewer(c, table)
+	table.Unlock()
+	if !ok {
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return // The upgrader has already sent the error back
+	}
+	defer conn.Close()
+
+	// Keep reading so we notice when the client goes away (the client isn't expected to send anything)
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	refresh := time.NewTicker(wsRefreshInterval)
+	defer refresh.Stop()
+	var lastSent []byte
+	for {
+		table.Lock()
+		playerIndex, ok := lookUpViewer(table, token) // Look the player up again, they may have moved seats or left
+		if !ok {
+			table.Unlock()
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, apiError{ERR_BAD_TOKEN, "You are no longer at this table"}.String()), time.Now().Add(wsWriteTimeout))
+			return
+		}
+		state := buildGameState(table, playerIndex)
+		changed := tableChange(table)
+		table.Unlock()
+
+		// Only send the state when it is different to what the client already has
+		data, _ := json.Marshal(state)
+		if !bytes.Equal(data, lastSent) {
+			conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+			lastSent = data
+		}
+
+		select {
+		case <-changed:
+		case <-refresh.C:
+		case <-closed:
+			return
+		}
+	}
+}
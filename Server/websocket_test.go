@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// readState reads the next game state sent over the WebSocket
+func readState(t *testing.T, conn *websocket.Conn) gameStateResponse {
+	t.Helper()
+	var state gameStateResponse
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.ReadJSON(&state); err != nil {
+		t.Fatalf("no state over the WebSocket: %v", err)
+	}
+	return state
+}
+
+// TestWebSocketPushesChanges checks the player gets the state as soon as they connect and again when the game starts
+func TestWebSocketPushesChanges(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	setUpTables()
+	router := newRouter()
+	server := httptest.NewServer(router)
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws?table=ai1&tk="
+
+	// A bad token is turned away before the upgrade
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL+"nope", nil)
+	if err == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("connected with a bad token: %v", err)
+	}
+
+	token := join(t, router, "ai1", "Alice")
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+token, nil)
+	if err != nil {
+		t.Fatalf("couldn't connect: %v", err)
+	}
+	defer conn.Close()
+
+	state := readState(t, conn)
+	if len(state.Players) != 1 || state.Players[0].Name != "Alice" {
+		t.Fatalf("first state has players %+v", state.Players)
+	}
+
+	get(router, "/start?table=ai1&tk="+token)
+	state = readState(t, conn)
+	if len(state.Players) != 2 {
+		t.Fatalf("state after the start has %d players, want Alice and a bot", len(state.Players))
+	}
+}